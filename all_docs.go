@@ -0,0 +1,80 @@
+package couchdb
+
+import (
+	"fmt"
+)
+
+//The page size ForEachDoc uses
+const forEachDocPageSize = 100
+
+//Queries _all_docs.  Ranges and keys are document IDs.
+func (db *Database) AllDocs(q *ViewQuery) (*ViewQueryResult, error) {
+	return db.queryDocs(q, db.dbName, "_all_docs")
+}
+
+//Queries _design_docs, which lists only the design documents.
+func (db *Database) DesignDocs(q *ViewQuery) (*ViewQueryResult, error) {
+	return db.queryDocs(q, db.dbName, "_design_docs")
+}
+
+//Queries _local_docs, which lists the _local documents.
+//Requires CouchDB 2.2 or later.
+func (db *Database) LocalDocs(q *ViewQuery) (*ViewQueryResult, error) {
+	return db.queryDocs(q, db.dbName, "_local_docs")
+}
+
+func (db *Database) queryDocs(q *ViewQuery,
+	pathSegments ...string) (*ViewQueryResult, error) {
+	resp, err := db.viewRequest(q, pathSegments...)
+	if err != nil {
+		return nil, err
+	}
+	result := ViewQueryResult{}
+	if err = parseBody(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+//Calls fn with every document in the database, design documents included,
+//in ID order.  Documents are fetched 100 at a time, so the database never
+//has to fit in memory.
+//An error returned by fn stops the scan and is returned.
+func (db *Database) ForEachDoc(fn func(row *ViewRow) error) error {
+	return db.ForEachDocWithPageSize(forEachDocPageSize, fn)
+}
+
+//Like ForEachDoc, fetching pageSize documents at a time
+//(100 if pageSize is not positive).
+//Larger pages mean fewer requests, smaller ones less memory.
+func (db *Database) ForEachDocWithPageSize(pageSize int,
+	fn func(row *ViewRow) error) error {
+	if pageSize <= 0 {
+		pageSize = forEachDocPageSize
+	}
+	q := ViewQuery{IncludeDocs: true, Limit: pageSize + 1}
+	for {
+		result, err := db.AllDocs(&q)
+		if err != nil {
+			return err
+		}
+		rows := result.Rows
+		if len(rows) > pageSize {
+			rows = rows[:pageSize]
+		}
+		for i := range rows {
+			if err := fn(&rows[i]); err != nil {
+				return err
+			}
+		}
+		if len(result.Rows) <= pageSize {
+			return nil
+		}
+		//the extra row starts the next page
+		next := result.Rows[pageSize].ID
+		if next == "" {
+			return fmt.Errorf("Unexpected _all_docs row without an ID")
+		}
+		q.StartKey = next
+	}
+}
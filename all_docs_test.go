@@ -0,0 +1,93 @@
+package couchdb
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAllDocs(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		_, err := db.Save(TestDocument{Title: id}, id, "")
+		errorify(t, err)
+	}
+	_, err := db.SaveDesignDoc("colors", DesignDocument{
+		Language: "javascript",
+		Views:    map[string]View{},
+		Lists:    map[string]string{},
+	}, "")
+	errorify(t, err)
+	err = db.SaveLocal(map[string]string{"hello": "world"}, "checkpoint")
+	errorify(t, err)
+
+	result, err := db.AllDocs(&ViewQuery{StartKey: "b", EndKey: "c", IncludeDocs: true})
+	errorify(t, err)
+	if result.TotalRows != 5 || len(result.Rows) != 2 {
+		t.Fatalf("Wrong _all_docs result: %v", result)
+	}
+	doc := TestDocument{}
+	errorify(t, result.Rows[0].DecodeDoc(&doc))
+	if doc.Title != "b" {
+		t.Errorf("Wrong doc: %v", doc)
+	}
+
+	result, err = db.DesignDocs(nil)
+	errorify(t, err)
+	if len(result.Rows) != 1 || result.Rows[0].ID != "_design/colors" {
+		t.Errorf("Wrong _design_docs result: %v", result.Rows)
+	}
+	if !serverAtLeast(conn, "2.2") {
+		return
+	}
+	result, err = db.LocalDocs(nil)
+	errorify(t, err)
+	if len(result.Rows) != 1 || result.Rows[0].ID != "_local/checkpoint" {
+		t.Errorf("Wrong _local_docs result: %v", result.Rows)
+	}
+}
+
+func TestForEachDoc(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+	createLotsDocs(t, db)
+
+	ids := []string{}
+	err := db.ForEachDocWithPageSize(3, func(row *ViewRow) error {
+		doc := TestDocument{}
+		if err := row.DecodeDoc(&doc); err != nil {
+			return err
+		}
+		if !strings.HasPrefix(doc.Title, "TheDoc") {
+			t.Errorf("Wrong doc: %v", doc)
+		}
+		ids = append(ids, row.ID)
+		return nil
+	})
+	errorify(t, err)
+	if len(ids) != 10 {
+		t.Errorf("Expected 10 docs, got %v", len(ids))
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Errorf("Docs out of order: %v", ids)
+		}
+	}
+
+	count := 0
+	err = db.ForEachDoc(func(row *ViewRow) error {
+		count++
+		if count == 4 {
+			return fmt.Errorf("stop")
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "stop" || count != 4 {
+		t.Errorf("The scan should stop at the handler's error: %v %v", err, count)
+	}
+}
@@ -102,37 +102,47 @@ func getBulkDocumentResult(resp *http.Response) ([]BulkDocumentResult, error) {
 func (b *BulkDocument) Commit() ([]BulkDocumentResult, error) {
 	if !b.closed {
 		b.closed = true
-		url, err := buildUrl(b.db.dbName, "_bulk_docs")
-		if err != nil {
-			return nil, err
-		}
-		var headers = make(map[string]string)
-		headers["Content-Type"] = "application/json"
-		headers["Accept"] = "application/json"
-		bd := make(map[string]interface{})
-		bd["docs"] = b.docs
-		data, numBytes, err := encodeData(bd)
-		if err != nil {
-			return nil, err
-		}
-		headers["Content-Length"] = strconv.Itoa(numBytes)
-		//Yes, this needs to be here.
-		//Yes, I know the Golang http.Client doesn't support expect/continue
-		//This is here to work around a bug in CouchDB.  It shouldn't work, and yet it does.
-		//See: http://stackoverflow.com/questions/30541591/large-put-requests-from-go-to-couchdb
-		//Also, I filed a bug report: https://issues.apache.org/jira/browse/COUCHDB-2704
-		//Go net/http needs to support the HTTP/1.1 spec, or CouchDB needs to get fixed.
-		//If either of those happens in the future, I can revisit this.
-		//Unless I forget, which I'm sure I will.
-		if numBytes > 4000 {
-			headers["Expect"] = "100-continue"
-		}
-		resp, err := b.db.connection.request("POST", url, data, headers, b.db.auth)
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-		return getBulkDocumentResult(resp)
+		return b.db.bulkDocs(b.docs, true)
 	}
 	return nil, fmt.Errorf("CouchDB: Bulk Document has already been executed")
 }
+
+// bulkDocs POST /{db}/_bulk_docs
+// With newEdits false, documents are stored with the revisions they carry,
+// as replication requires.
+func (db *Database) bulkDocs(docs interface{}, newEdits bool) ([]BulkDocumentResult, error) {
+	url, err := buildUrl(db.dbName, "_bulk_docs")
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Content-Type"] = "application/json"
+	headers["Accept"] = "application/json"
+	bd := make(map[string]interface{})
+	bd["docs"] = docs
+	if !newEdits {
+		bd["new_edits"] = false
+	}
+	data, numBytes, err := encodeData(bd)
+	if err != nil {
+		return nil, err
+	}
+	headers["Content-Length"] = strconv.Itoa(numBytes)
+	//Yes, this needs to be here.
+	//Yes, I know the Golang http.Client doesn't support expect/continue
+	//This is here to work around a bug in CouchDB.  It shouldn't work, and yet it does.
+	//See: http://stackoverflow.com/questions/30541591/large-put-requests-from-go-to-couchdb
+	//Also, I filed a bug report: https://issues.apache.org/jira/browse/COUCHDB-2704
+	//Go net/http needs to support the HTTP/1.1 spec, or CouchDB needs to get fixed.
+	//If either of those happens in the future, I can revisit this.
+	//Unless I forget, which I'm sure I will.
+	if numBytes > 4000 {
+		headers["Expect"] = "100-continue"
+	}
+	resp, err := db.connection.request("POST", url, data, headers, db.auth)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return getBulkDocumentResult(resp)
+}
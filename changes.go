@@ -0,0 +1,577 @@
+package couchdb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//A CouchDB update sequence.
+//CouchDB 1.x uses integers while 2.x uses opaque strings, so both are accepted.
+type Seq string
+
+func (s *Seq) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		*s = Seq(str)
+	} else if string(data) == "null" {
+		*s = ""
+	} else {
+		*s = Seq(data)
+	}
+	return nil
+}
+
+//Options for reading the changes feed.
+//See: http://docs.couchdb.org/en/2.1.1/api/database/changes.html
+type ChangesOptions struct {
+	Feed        string //normal (default), longpoll, continuous or eventsource
+	Since       string //a sequence, or "now"
+	Heartbeat   int    //milliseconds
+	Timeout     int    //milliseconds
+	IncludeDocs bool
+	Style       string //main_only (default) or all_docs
+	Conflicts   bool
+	Descending  bool
+	Limit       int
+	//Filtering.  Filter is either "ddoc/name" for a design document filter
+	//function or one of the built-in filters: _doc_ids, _selector, _view or _design.
+	//When DocIDs or Selector is set, Filter may be left empty.
+	Filter      string
+	QueryParams url.Values  //extra parameters passed to a design document filter
+	DocIDs      []string    //for the _doc_ids filter
+	Selector    interface{} //for the _selector filter, same shape as FindQueryParams.Selector
+	View        string      //"ddoc/view" for the _view filter
+}
+
+//A single row from the changes feed
+type Change struct {
+	Seq     Seq             `json:"seq"`
+	ID      string          `json:"id"`
+	Changes []ChangeRev     `json:"changes"`
+	Deleted bool            `json:"deleted,omitempty"`
+	Doc     json.RawMessage `json:"doc,omitempty"`
+}
+
+type ChangeRev struct {
+	Rev string `json:"rev"`
+}
+
+//Unmarshals the document included with include_docs into doc
+func (c *Change) DecodeDoc(doc interface{}) error {
+	if len(c.Doc) == 0 || string(c.Doc) == "null" {
+		return fmt.Errorf("No document included with change %v", c.ID)
+	}
+	return json.Unmarshal(c.Doc, doc)
+}
+
+type ChangesResponse struct {
+	Results []Change `json:"results"`
+	LastSeq Seq      `json:"last_seq"`
+	Pending int      `json:"pending"`
+}
+
+func (opts *ChangesOptions) values() url.Values {
+	params := url.Values{}
+	for k, v := range opts.QueryParams {
+		params[k] = v
+	}
+	if filter := opts.filter(); filter != "" {
+		params.Set("filter", filter)
+	}
+	if opts.View != "" {
+		params.Set("view", opts.View)
+	}
+	if opts.Feed != "" {
+		params.Set("feed", opts.Feed)
+	}
+	if opts.Since != "" {
+		params.Set("since", opts.Since)
+	}
+	if opts.Heartbeat > 0 {
+		params.Set("heartbeat", strconv.Itoa(opts.Heartbeat))
+	}
+	if opts.Timeout > 0 {
+		params.Set("timeout", strconv.Itoa(opts.Timeout))
+	}
+	if opts.IncludeDocs {
+		params.Set("include_docs", "true")
+	}
+	if opts.Style != "" {
+		params.Set("style", opts.Style)
+	}
+	if opts.Conflicts {
+		params.Set("conflicts", "true")
+	}
+	if opts.Descending {
+		params.Set("descending", "true")
+	}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	return params
+}
+
+func (opts *ChangesOptions) validate() error {
+	switch opts.Feed {
+	case "", "normal", "longpoll", "continuous", "eventsource":
+	default:
+		return fmt.Errorf("Invalid feed type: %v", opts.Feed)
+	}
+	switch opts.Style {
+	case "", "main_only", "all_docs":
+	default:
+		return fmt.Errorf("Invalid style: %v", opts.Style)
+	}
+	if opts.Heartbeat < 0 || opts.Timeout < 0 || opts.Limit < 0 {
+		return fmt.Errorf("Heartbeat, timeout and limit must not be negative")
+	}
+	return opts.validateFilter()
+}
+
+//the filter to use, inferred from DocIDs or Selector if not given
+func (opts *ChangesOptions) filter() string {
+	if opts.Filter != "" {
+		return opts.Filter
+	}
+	if opts.DocIDs != nil {
+		return "_doc_ids"
+	}
+	if opts.Selector != nil {
+		return "_selector"
+	}
+	return ""
+}
+
+func (opts *ChangesOptions) validateFilter() error {
+	filter := opts.filter()
+	if filter != "_doc_ids" && opts.DocIDs != nil {
+		return fmt.Errorf("DocIDs can only be used with the _doc_ids filter")
+	}
+	if filter != "_selector" && opts.Selector != nil {
+		return fmt.Errorf("Selector can only be used with the _selector filter")
+	}
+	if filter != "_view" && opts.View != "" {
+		return fmt.Errorf("View can only be used with the _view filter")
+	}
+	switch filter {
+	case "":
+		if len(opts.QueryParams) > 0 {
+			return fmt.Errorf("QueryParams require a filter")
+		}
+		return nil
+	case "_doc_ids":
+		if len(opts.DocIDs) == 0 {
+			return fmt.Errorf("The _doc_ids filter requires DocIDs")
+		}
+	case "_selector":
+		if opts.Selector == nil {
+			return fmt.Errorf("The _selector filter requires a Selector")
+		}
+	case "_view":
+		if !isDesignPath(opts.View) {
+			return fmt.Errorf("The _view filter requires a View of the form ddoc/view")
+		}
+	case "_design":
+	default:
+		if !isDesignPath(filter) {
+			return fmt.Errorf("Invalid filter: %v, expected ddoc/name", filter)
+		}
+		for k := range opts.QueryParams {
+			if reservedChangesParams[k] {
+				return fmt.Errorf("Query parameter %v is reserved by the changes feed", k)
+			}
+		}
+		return nil
+	}
+	if len(opts.QueryParams) > 0 {
+		return fmt.Errorf("QueryParams can only be used with design document filters")
+	}
+	return nil
+}
+
+//parameters that a design document filter's QueryParams may not override
+var reservedChangesParams = map[string]bool{
+	"feed": true, "since": true, "heartbeat": true, "timeout": true,
+	"include_docs": true, "style": true, "conflicts": true,
+	"descending": true, "limit": true, "filter": true, "view": true,
+}
+
+//checks for a "ddoc/name" pair
+func isDesignPath(path string) bool {
+	parts := strings.Split(path, "/")
+	return len(parts) == 2 && parts[0] != "" && parts[1] != ""
+}
+
+//the request body needed by the filter, if any
+func (opts *ChangesOptions) body() interface{} {
+	switch opts.filter() {
+	case "_doc_ids":
+		return map[string]interface{}{"doc_ids": opts.DocIDs}
+	case "_selector":
+		return map[string]interface{}{"selector": opts.Selector}
+	}
+	return nil
+}
+
+//issues a request to the changes feed.
+//Filters that need a request body are sent as a POST.
+func (db *Database) changesRequest(opts *ChangesOptions) (*http.Response, error) {
+	url, err := buildParamUrl(opts.values(), db.dbName, "_changes")
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	body := opts.body()
+	if body == nil {
+		return db.connection.request("GET", url, nil, headers, db.auth)
+	}
+	data, numBytes, err := encodeData(body)
+	if err != nil {
+		return nil, err
+	}
+	headers["Content-Type"] = "application/json"
+	headers["Content-Length"] = strconv.Itoa(numBytes)
+	if numBytes > 4000 {
+		headers["Expect"] = "100-continue"
+	}
+	return db.connection.request("POST", url, data, headers, db.auth)
+}
+
+//Reads the changes feed in normal or longpoll mode.
+//Use ChangesFeed for continuous and eventsource feeds.
+func (db *Database) Changes(opts *ChangesOptions) (*ChangesResponse, error) {
+	if opts == nil {
+		opts = &ChangesOptions{}
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if opts.Feed == "continuous" || opts.Feed == "eventsource" {
+		return nil, fmt.Errorf("Use ChangesFeed for %v feeds", opts.Feed)
+	}
+	resp, err := db.changesRequest(opts)
+	if err != nil {
+		return nil, err
+	}
+	changes := ChangesResponse{}
+	if err = parseBody(resp, &changes); err != nil {
+		return nil, err
+	}
+	return &changes, nil
+}
+
+//Streams rows from a continuous or eventsource changes feed.
+//If the connection drops, the feed reconnects from the last seen sequence,
+//and keeps trying while the server is unreachable.  Unless Limit or Timeout
+//is set, the feed also reconnects when the server ends it.
+//The feed ends when the database's context is done (see Database.WithContext).
+//Use it like so:
+//	for feed.Next() {
+//		change := feed.Change()
+//		...
+//	}
+//	if err := feed.Err(); err != nil {
+//		...
+//	}
+type ChangesFeed struct {
+	db        *Database
+	options   ChangesOptions
+	stream    *feedStream
+	change    *Change
+	delivered int
+}
+
+//Opens a continuous (default) or eventsource changes feed.
+//The caller must Close the feed when done with it.
+func (db *Database) ChangesFeed(opts *ChangesOptions) (*ChangesFeed, error) {
+	var options ChangesOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.Feed == "" {
+		options.Feed = "continuous"
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	if options.Feed != "continuous" && options.Feed != "eventsource" {
+		return nil, fmt.Errorf("Use Changes for %v feeds", options.Feed)
+	}
+	f := &ChangesFeed{db: db, options: options}
+	f.stream = newFeedStream(db.Context(), "Changes feed", Seq(options.Since),
+		options.Feed == "eventsource", options.Limit > 0 || options.Timeout > 0, f.open)
+	if err := f.stream.connect(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+//(re)opens the feed from the last seen sequence
+func (f *ChangesFeed) open() (*http.Response, error) {
+	opts := f.options
+	opts.Since = string(f.stream.lastSeq)
+	if opts.Limit > 0 {
+		opts.Limit -= f.delivered
+	}
+	return f.db.changesRequest(&opts)
+}
+
+//Advances to the next change.
+//Returns false when the feed ends, is closed, or an error occurs.
+func (f *ChangesFeed) Next() bool {
+	f.change = nil
+	if f.options.Limit > 0 && f.delivered >= f.options.Limit {
+		return false
+	}
+	change := Change{}
+	if !f.stream.nextRow(&change) {
+		return false
+	}
+	f.change = &change
+	f.delivered++
+	return true
+}
+
+//Returns the current change
+func (f *ChangesFeed) Change() *Change {
+	return f.change
+}
+
+//Returns the last sequence seen on the feed
+func (f *ChangesFeed) LastSeq() Seq {
+	return f.stream.lastSeq
+}
+
+//Returns the error, if any, that ended the feed
+func (f *ChangesFeed) Err() error {
+	return f.stream.err
+}
+
+//Closes the feed.
+//Safe to call from another goroutine to interrupt a blocked Next.
+func (f *ChangesFeed) Close() error {
+	return f.stream.close()
+}
+
+//reads newline delimited rows from a streaming feed,
+//reopening the connection when it drops
+type feedStream struct {
+	ctx          context.Context
+	name         string //for error messages
+	eventSource  bool
+	endOnLastSeq bool //rather than reconnecting when the server ends the feed
+	open         func() (*http.Response, error)
+	mu           sync.Mutex
+	resp         *http.Response
+	reader       *bufio.Reader
+	closed       bool
+	done         chan struct{}
+	reconnects   int //since the last row read
+	lastSeq      Seq
+	err          error
+}
+
+func newFeedStream(ctx context.Context, name string, since Seq,
+	eventSource bool, endOnLastSeq bool,
+	open func() (*http.Response, error)) *feedStream {
+	return &feedStream{
+		ctx:          ctx,
+		name:         name,
+		eventSource:  eventSource,
+		endOnLastSeq: endOnLastSeq,
+		open:         open,
+		done:         make(chan struct{}),
+		lastSeq:      since,
+	}
+}
+
+//Decodes the next row of the feed into row, remembering its sequence.
+//Error rows end the feed, and so does the server ending it if endOnLastSeq
+//is set; otherwise the feed is picked up again, since without a heartbeat
+//an idle feed times out after the server's changes_timeout.
+//Returns false when the feed ends, leaving the reason, if any, in err.
+func (s *feedStream) nextRow(row interface{}) bool {
+	if s.err != nil {
+		return false
+	}
+	for {
+		line, err := s.next()
+		if err != nil {
+			if err != io.EOF {
+				s.err = err
+			}
+			return false
+		}
+		var meta struct {
+			Seq     Seq    `json:"seq"`
+			LastSeq *Seq   `json:"last_seq"`
+			Error   string `json:"error"`
+			Reason  string `json:"reason"`
+		}
+		if err = json.Unmarshal(line, &meta); err != nil {
+			s.err = err
+			return false
+		}
+		if meta.Error != "" {
+			s.err = fmt.Errorf("%v error: %v - %v", s.name, meta.Error, meta.Reason)
+			return false
+		}
+		if meta.LastSeq != nil {
+			//the server ended the feed
+			s.lastSeq = *meta.LastSeq
+			if s.endOnLastSeq {
+				return false
+			}
+			if err = s.reconnect(); err != nil {
+				if err != io.EOF {
+					s.err = err
+				}
+				return false
+			}
+			continue
+		}
+		if err = json.Unmarshal(line, row); err != nil {
+			s.err = err
+			return false
+		}
+		//CouchDB 1.x sends _db_updates rows without one
+		if meta.Seq != "" {
+			s.lastSeq = meta.Seq
+		}
+		return true
+	}
+}
+
+func (s *feedStream) connect() error {
+	resp, err := s.open()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		resp.Body.Close()
+		return io.EOF
+	}
+	if s.resp != nil {
+		s.resp.Body.Close()
+	}
+	s.resp = resp
+	s.reader = bufio.NewReader(resp.Body)
+	return nil
+}
+
+//Returns the next row, skipping heartbeats.
+//Returns io.EOF once the stream has been closed.
+func (s *feedStream) next() ([]byte, error) {
+	for {
+		s.mu.Lock()
+		reader, closed := s.reader, s.closed
+		s.mu.Unlock()
+		if closed {
+			return nil, io.EOF
+		}
+		line, err := reader.ReadBytes('\n')
+		if row := s.payload(line); len(row) > 0 {
+			//a row cut short by a dropped connection fails to parse;
+			//drop it and pick it up again after reconnecting
+			if err == nil || json.Valid(row) {
+				s.reconnects = 0
+				return row, nil
+			}
+		}
+		if err == nil {
+			continue
+		}
+		if s.isClosed() {
+			return nil, io.EOF
+		}
+		if err = s.ctx.Err(); err != nil {
+			return nil, err
+		}
+		//the connection dropped
+		if err = s.reconnect(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+//Backs off a little and reopens the stream, for as long as the server
+//stays unreachable.  Stops when the stream is closed, the context is done,
+//or the server answers with an error that retrying won't fix.
+func (s *feedStream) reconnect() error {
+	for {
+		s.reconnects++
+		delay := time.Duration(s.reconnects) * 100 * time.Millisecond
+		if delay > 5*time.Second {
+			delay = 5 * time.Second
+		}
+		select {
+		case <-time.After(delay):
+		case <-s.done:
+			return io.EOF
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		}
+		err := s.connect()
+		if err == nil || !canReconnect(err) {
+			return err
+		}
+	}
+}
+
+//Network and server errors are worth reconnecting after,
+//while e.g. a deleted database is not.
+func canReconnect(err error) bool {
+	if couchErr, ok := err.(*Error); ok {
+		return couchErr.StatusCode >= 500
+	}
+	return err != io.EOF
+}
+
+//extracts the JSON payload from a line
+func (s *feedStream) payload(line []byte) []byte {
+	line = bytes.TrimSpace(line)
+	if !s.eventSource {
+		return line
+	}
+	if bytes.HasPrefix(line, []byte("data:")) {
+		return bytes.TrimSpace(line[len("data:"):])
+	}
+	//id, event and comment lines carry nothing we need
+	return nil
+}
+
+func (s *feedStream) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *feedStream) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	if s.resp != nil {
+		return s.resp.Body.Close()
+	}
+	return nil
+}
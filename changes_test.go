@@ -0,0 +1,305 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSeqUnmarshal(t *testing.T) {
+	var seqs []Seq
+	err := json.Unmarshal([]byte(`[42, "13-g1AAAAE", null]`), &seqs)
+	errorify(t, err)
+	if seqs[0] != "42" || seqs[1] != "13-g1AAAAE" || seqs[2] != "" {
+		t.Errorf("Sequences not decoded correctly: %v", seqs)
+	}
+}
+
+func TestChangesOptions(t *testing.T) {
+	opts := ChangesOptions{
+		Feed:        "longpoll",
+		Since:       "now",
+		Heartbeat:   1000,
+		IncludeDocs: true,
+		Style:       "all_docs",
+		Limit:       5,
+	}
+	errorify(t, opts.validate())
+	stringified, err := buildParamUrl(opts.values(), "theDb", "_changes")
+	errorify(t, err)
+	if stringified != "/theDb/_changes?feed=longpoll&heartbeat=1000&include_docs=true&limit=5&since=now&style=all_docs" {
+		t.Errorf("Wrong URL: %v", stringified)
+	}
+	opts.Feed = "sideways"
+	if opts.validate() == nil {
+		t.Error("Invalid feed type should not validate")
+	}
+}
+
+func TestChanges(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+	createLotsDocs(t, db)
+
+	changes, err := db.Changes(&ChangesOptions{IncludeDocs: true})
+	errorify(t, err)
+	if len(changes.Results) != 10 {
+		t.Fatalf("Should be 10 changes, got %v", len(changes.Results))
+	}
+	doc := TestDocument{}
+	errorify(t, changes.Results[0].DecodeDoc(&doc))
+	if doc.Title == "" {
+		t.Error("Included document was not decoded")
+	}
+	//now, only the changes after the first
+	since := string(changes.Results[0].Seq)
+	changes, err = db.Changes(&ChangesOptions{Since: since})
+	errorify(t, err)
+	if len(changes.Results) != 9 {
+		t.Errorf("Should be 9 changes, got %v", len(changes.Results))
+	}
+	if _, err = db.Changes(&ChangesOptions{Feed: "continuous"}); err == nil {
+		t.Error("Changes should refuse continuous feeds")
+	}
+}
+
+func TestChangesFeed(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+	createLotsDocs(t, db)
+
+	for _, feedType := range []string{"continuous", "eventsource"} {
+		feed, err := db.ChangesFeed(&ChangesOptions{
+			Feed:      feedType,
+			Heartbeat: 100,
+			Limit:     10,
+		})
+		errorify(t, err)
+		if err != nil {
+			continue
+		}
+		count := 0
+		for feed.Next() {
+			if feed.Change().ID == "" {
+				t.Errorf("%v change has no id", feedType)
+			}
+			count++
+		}
+		errorify(t, feed.Err())
+		errorify(t, feed.Close())
+		if count != 10 {
+			t.Errorf("Should be 10 %v changes, got %v", feedType, count)
+		}
+		if feed.LastSeq() == "" {
+			t.Errorf("No last seq for %v feed", feedType)
+		}
+	}
+}
+
+func TestChangesFilterValidation(t *testing.T) {
+	valid := []ChangesOptions{
+		{DocIDs: []string{"a", "b"}},
+		{Selector: map[string]interface{}{"Note": "magenta"}},
+		{Filter: "_view", View: "colors/find_all_magenta"},
+		{Filter: "_design"},
+		{Filter: "app/important", QueryParams: url.Values{"level": {"high"}}},
+	}
+	for _, opts := range valid {
+		errorify(t, opts.validate())
+	}
+	invalid := []ChangesOptions{
+		{Filter: "_doc_ids"},
+		{Filter: "_selector"},
+		{Filter: "_view"},
+		{Filter: "_view", View: "noslash"},
+		{Filter: "noslash"},
+		{Filter: "_selector", DocIDs: []string{"a"}},
+		{View: "colors/find_all_magenta"},
+		{QueryParams: url.Values{"level": {"high"}}},
+		{Filter: "app/important", QueryParams: url.Values{"since": {"0"}}},
+		{DocIDs: []string{"a"}, QueryParams: url.Values{"level": {"high"}}},
+	}
+	for _, opts := range invalid {
+		if opts.validate() == nil {
+			t.Errorf("Options should not validate: %+v", opts)
+		}
+	}
+	opts := ChangesOptions{Filter: "app/important", QueryParams: url.Values{"level": {"high"}}}
+	if opts.body() != nil {
+		t.Error("Design document filters should not need a body")
+	}
+	opts = ChangesOptions{DocIDs: []string{"a"}}
+	if opts.values().Get("filter") != "_doc_ids" || opts.body() == nil {
+		t.Error("DocIDs should be POSTed with the _doc_ids filter")
+	}
+}
+
+func TestFilteredChanges(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+	createLotsDocs(t, db)
+	id := getUuid()
+	_, err := db.Save(TestDocument{Title: "Filtered", Note: "magenta"}, id, "")
+	errorify(t, err)
+
+	changes, err := db.Changes(&ChangesOptions{DocIDs: []string{id}})
+	errorify(t, err)
+	if len(changes.Results) != 1 || changes.Results[0].ID != id {
+		t.Errorf("Expected only the change for %v: %v", id, changes)
+	}
+	changes, err = db.Changes(&ChangesOptions{
+		Selector: map[string]interface{}{"Note": "magenta"},
+	})
+	errorify(t, err)
+	if len(changes.Results) != 6 {
+		t.Errorf("Should be 6 magenta changes, got %v", len(changes.Results))
+	}
+	ddoc := DesignDocument{
+		Language: "javascript",
+		Views: map[string]View{
+			"purple": {Map: "function(doc) { if (doc.Note === \"purple\") { emit(doc._id) } }"},
+		},
+		Lists: map[string]string{},
+	}
+	_, err = db.SaveDesignDoc("colors", ddoc, "")
+	errorify(t, err)
+	changes, err = db.Changes(&ChangesOptions{Filter: "_view", View: "colors/purple"})
+	errorify(t, err)
+	if len(changes.Results) != 5 {
+		t.Errorf("Should be 5 purple changes, got %v", len(changes.Results))
+	}
+}
+
+func TestChangesFeedContext(t *testing.T) {
+	//a feed that only ever sends heartbeats
+	feedServer := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			for {
+				if _, err := w.Write([]byte("\n")); err != nil {
+					return
+				}
+				w.(http.Flusher).Flush()
+				select {
+				case <-time.After(10 * time.Millisecond):
+				case <-r.Context().Done():
+					return
+				}
+			}
+		}))
+	defer feedServer.Close()
+	conn, err := createConnection(feedServer.URL, 0)
+	errorify(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	feed, err := conn.SelectDB("theDb", nil).WithContext(ctx).ChangesFeed(nil)
+	if err != nil {
+		t.Fatalf("ERROR: %v", err)
+	}
+	defer feed.Close()
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if feed.Next() {
+		t.Error("There should be no changes")
+	}
+	if feed.Err() != context.Canceled {
+		t.Errorf("The feed should end with the context: %v", feed.Err())
+	}
+}
+
+func TestChangesFeedServerRestart(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ERROR: %v", err)
+	}
+	addr := listener.Addr().String()
+	restart := make(chan bool, 1)
+	sinces := make(chan string, 10)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		since := r.URL.Query().Get("since")
+		sinces <- since
+		if since == "" {
+			fmt.Fprintln(w, `{"seq":"1-x","id":"a","changes":[{"rev":"1-a"}]}`)
+			w.(http.Flusher).Flush()
+			restart <- true
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprintln(w, `{"seq":"2-x","id":"b","changes":[{"rev":"1-b"}]}`)
+	})
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	restarted := make(chan *http.Server, 1)
+	go func() {
+		<-restart
+		server.Close()
+		//down for a while, refusing connections
+		time.Sleep(400 * time.Millisecond)
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return
+		}
+		server := &http.Server{Handler: handler}
+		restarted <- server
+		server.Serve(listener)
+	}()
+
+	conn, err := createConnection("http://"+addr, 0)
+	errorify(t, err)
+	feed, err := conn.SelectDB("theDb", nil).ChangesFeed(&ChangesOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("ERROR: %v", err)
+	}
+	defer feed.Close()
+	ids := ""
+	for feed.Next() {
+		ids += feed.Change().ID
+	}
+	errorify(t, feed.Err())
+	if ids != "ab" {
+		t.Errorf("Wrong changes after the restart: %v", ids)
+	} else {
+		(<-restarted).Close()
+	}
+	<-sinces
+	if since := <-sinces; since != "1-x" {
+		t.Errorf("Should reconnect from the last seq, not %v", since)
+	}
+}
+
+func TestChangesFeedReconnectsAfterTimeout(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests == 1 {
+				//an idle feed timed out by the server
+				fmt.Fprintln(w, `{"last_seq":"5-x","pending":0}`)
+				return
+			}
+			fmt.Fprintln(w, `{"seq":"6-x","id":"a","changes":[{"rev":"1-a"}]}`)
+		}))
+	defer server.Close()
+	conn, err := createConnection(server.URL, time.Second)
+	errorify(t, err)
+	feed, err := conn.SelectDB("theDb", nil).ChangesFeed(nil)
+	if err != nil {
+		t.Fatalf("ERROR: %v", err)
+	}
+	defer feed.Close()
+	if !feed.Next() || feed.Change().ID != "a" {
+		t.Errorf("The feed should carry on after a timeout: %v", feed.Err())
+	}
+	if feed.LastSeq() != "6-x" {
+		t.Errorf("Wrong last seq: %v", feed.LastSeq())
+	}
+}
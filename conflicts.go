@@ -0,0 +1,241 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+//Decides how a conflicted document is resolved.
+//winner is the revision CouchDB picked; losers are the other leaf revisions.
+//Returns the document to save as the resolved winner.  Its _id and _rev
+//are set for you, and the losers are deleted.
+//The revisions are read with their attachment bodies inline, so the result
+//may keep the attachments of any of them.  Stubs added by the strategy
+//must exist on the winning revision.
+type ConflictStrategy func(winner OpenRev, losers []OpenRev) (interface{}, error)
+
+//Keeps the revision CouchDB picked as the winner and deletes the rest.
+func KeepWinner(winner OpenRev, losers []OpenRev) (interface{}, error) {
+	return winner.Doc, nil
+}
+
+//Keeps the revision with the latest value of a top level field,
+//which holds either a number or an RFC 3339 timestamp.
+//Revisions without the field lose; ties go to CouchDB's winner.
+func LastWriteWins(field string) ConflictStrategy {
+	return func(winner OpenRev, losers []OpenRev) (interface{}, error) {
+		latest := winner
+		latestTime, latestOk := writeTime(winner, field)
+		for _, loser := range losers {
+			if t, ok := writeTime(loser, field); ok && (!latestOk || t > latestTime) {
+				latest, latestTime, latestOk = loser, t, true
+			}
+		}
+		return latest.Doc, nil
+	}
+}
+
+//reads a timestamp field as nanoseconds, or a number as is
+func writeTime(rev OpenRev, field string) (float64, bool) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(rev.Doc, &doc); err != nil {
+		return 0, false
+	}
+	switch value := doc[field].(type) {
+	case float64:
+		return value, true
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return float64(t.UnixNano()), true
+		}
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f, true
+		}
+	}
+	return 0, false
+}
+
+//The outcome of resolving one document
+type ConflictResolution struct {
+	ID      string
+	Rev     string   //the new winning revision
+	Deleted []string //the losing revisions, now deleted
+}
+
+//Finds conflicted documents and resolves them with a strategy
+type ConflictResolver struct {
+	db       *Database
+	strategy ConflictStrategy
+}
+
+func (db *Database) NewConflictResolver(strategy ConflictStrategy) *ConflictResolver {
+	return &ConflictResolver{db: db, strategy: strategy}
+}
+
+//Metadata that doesn't belong in a document being saved
+var readOnlyFields = []string{"_conflicts", "_deleted_conflicts", "_revisions",
+	"_revs_info", "_local_seq"}
+
+//Resolves the conflicts of a single document.
+//Returns nil if the document has no conflicts.
+func (r *ConflictResolver) Resolve(id string) (*ConflictResolution, error) {
+	var current struct {
+		Rev       string   `json:"_rev"`
+		Conflicts []string `json:"_conflicts"`
+	}
+	if _, err := r.db.ReadWithOptions(id, &current,
+		&ReadOptions{Conflicts: true}); err != nil {
+		return nil, err
+	}
+	if len(current.Conflicts) == 0 {
+		return nil, nil
+	}
+	//a loser's attachment stubs would point at its own branch,
+	//which the resolved revision isn't saved on, so read the bodies
+	leaves, err := r.db.ReadOpenRevs(id,
+		append([]string{current.Rev}, current.Conflicts...),
+		&ReadOptions{Attachments: true})
+	if err != nil {
+		return nil, err
+	}
+	var winner *OpenRev
+	losers := []OpenRev{}
+	for i := range leaves {
+		switch {
+		case leaves[i].Missing:
+			//already gone
+		case leaves[i].Rev == current.Rev:
+			winner = &leaves[i]
+		default:
+			losers = append(losers, leaves[i])
+		}
+	}
+	if winner == nil {
+		return nil, fmt.Errorf("Winning revision %v of %v has gone", current.Rev, id)
+	}
+	resolved, err := r.strategy(*winner, losers)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := resolvedDoc(resolved, id, winner.Rev)
+	if err != nil {
+		return nil, err
+	}
+	docs := []interface{}{merged}
+	for _, loser := range losers {
+		docs = append(docs, map[string]interface{}{
+			"_id":      id,
+			"_rev":     loser.Rev,
+			"_deleted": true,
+		})
+	}
+	results, err := r.db.bulkDocs(docs, true)
+	if err != nil {
+		return nil, err
+	}
+	resolution := ConflictResolution{ID: id, Deleted: []string{}}
+	for i, result := range results {
+		if result.Error != nil {
+			reason := ""
+			if result.Reason != nil {
+				reason = *result.Reason
+			}
+			return nil, fmt.Errorf("Resolving %v failed: %v %v", id, *result.Error, reason)
+		}
+		if i == 0 {
+			resolution.Rev = result.Revision
+		} else {
+			resolution.Deleted = append(resolution.Deleted, losers[i-1].Rev)
+		}
+	}
+	return &resolution, nil
+}
+
+//turns the strategy's result into the document to save
+func resolvedDoc(resolved interface{}, id string,
+	rev string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(resolved)
+	if err != nil {
+		return nil, err
+	}
+	doc := make(map[string]json.RawMessage)
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("The resolved document must be a JSON object")
+	}
+	for _, field := range readOnlyFields {
+		delete(doc, field)
+	}
+	doc["_id"], _ = json.Marshal(id)
+	doc["_rev"], _ = json.Marshal(rev)
+	return doc, nil
+}
+
+//Scans the changes feed for conflicted documents and resolves them all
+func (r *ConflictResolver) ResolveAll() ([]ConflictResolution, error) {
+	resolutions := []ConflictResolution{}
+	opts := ChangesOptions{IncludeDocs: true, Conflicts: true, Limit: 100}
+	for {
+		changes, err := r.db.Changes(&opts)
+		if err != nil {
+			return resolutions, err
+		}
+		for i := range changes.Results {
+			change := &changes.Results[i]
+			if change.Deleted {
+				continue
+			}
+			var doc struct {
+				Conflicts []string `json:"_conflicts"`
+			}
+			if err := change.DecodeDoc(&doc); err != nil || len(doc.Conflicts) == 0 {
+				continue
+			}
+			resolution, err := r.Resolve(change.ID)
+			if err != nil {
+				return resolutions, err
+			}
+			if resolution != nil {
+				resolutions = append(resolutions, *resolution)
+			}
+		}
+		if len(changes.Results) < opts.Limit {
+			return resolutions, nil
+		}
+		opts.Since = string(changes.LastSeq)
+	}
+}
+
+//Resolves the documents emitting rows in a view,
+//e.g. one that emits only documents with _conflicts.
+func (r *ConflictResolver) ResolveView(designDoc string, view string,
+	q *ViewQuery) ([]ConflictResolution, error) {
+	resolutions := []ConflictResolution{}
+	rows, err := r.db.StreamView(designDoc, view, q)
+	if err != nil {
+		return resolutions, err
+	}
+	defer rows.Close()
+	ids := []string{}
+	seen := make(map[string]bool)
+	for rows.Next() {
+		if rows.ID() != "" && !seen[rows.ID()] {
+			seen[rows.ID()] = true
+			ids = append(ids, rows.ID())
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return resolutions, err
+	}
+	for _, id := range ids {
+		resolution, err := r.Resolve(id)
+		if err != nil {
+			return resolutions, err
+		}
+		if resolution != nil {
+			resolutions = append(resolutions, *resolution)
+		}
+	}
+	return resolutions, nil
+}
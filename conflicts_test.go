@@ -0,0 +1,163 @@
+package couchdb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+)
+
+func TestLastWriteWins(t *testing.T) {
+	winner := OpenRev{Rev: "2-a", Doc: json.RawMessage(
+		`{"_id":"doc","_rev":"2-a","updated":"2018-03-01T10:00:00Z"}`)}
+	newer := OpenRev{Rev: "2-b", Doc: json.RawMessage(
+		`{"_id":"doc","_rev":"2-b","updated":"2018-03-02T10:00:00Z"}`)}
+	undated := OpenRev{Rev: "2-c", Doc: json.RawMessage(`{"_id":"doc","_rev":"2-c"}`)}
+	resolved, err := LastWriteWins("updated")(winner, []OpenRev{undated, newer})
+	errorify(t, err)
+	if string(resolved.(json.RawMessage)) != string(newer.Doc) {
+		t.Errorf("The newest revision should win: %s", resolved)
+	}
+	resolved, err = LastWriteWins("updated")(undated, []OpenRev{winner})
+	errorify(t, err)
+	if string(resolved.(json.RawMessage)) != string(winner.Doc) {
+		t.Errorf("A dated revision should beat an undated one: %s", resolved)
+	}
+
+	doc, err := resolvedDoc(map[string]interface{}{
+		"Title": "merged", "_rev": "2-b", "_conflicts": []string{"2-a"},
+	}, "doc", "2-a")
+	errorify(t, err)
+	if string(doc["_rev"]) != `"2-a"` || string(doc["_id"]) != `"doc"` ||
+		doc["_conflicts"] != nil {
+		t.Errorf("Wrong resolved doc: %v", doc)
+	}
+	if _, err := resolvedDoc([]string{"not", "an", "object"}, "doc", "2-a"); err == nil {
+		t.Error("Expected an error for a non-object")
+	}
+}
+
+//saves a document with a second, conflicting revision
+func createConflict(t *testing.T, db *Database, title string, other string) string {
+	id := getUuid()
+	_, err := db.Save(TestDocument{Title: title, Note: "mine"}, id, "")
+	errorify(t, err)
+	_, err = db.bulkDocs([]map[string]interface{}{
+		{"_id": id, "_rev": "1-00000000000000000000000000000001",
+			"Title": other, "Note": "theirs"},
+	}, false)
+	errorify(t, err)
+	return id
+}
+
+func TestConflictResolver(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+	createLotsDocs(t, db)
+
+	id := createConflict(t, db, "first", "second")
+	merge := func(winner OpenRev, losers []OpenRev) (interface{}, error) {
+		merged := TestDocument{}
+		if err := winner.DecodeDoc(&merged); err != nil {
+			return nil, err
+		}
+		for _, loser := range losers {
+			doc := TestDocument{}
+			if err := loser.DecodeDoc(&doc); err != nil {
+				return nil, err
+			}
+			merged.Note += "+" + doc.Note
+		}
+		return merged, nil
+	}
+	resolution, err := db.NewConflictResolver(merge).Resolve(id)
+	errorify(t, err)
+	if resolution == nil || len(resolution.Deleted) != 1 {
+		t.Fatalf("Expected one deleted revision: %v", resolution)
+	}
+	doc := struct {
+		TestDocument
+		Conflicts []string `json:"_conflicts"`
+	}{}
+	rev, err := db.ReadWithOptions(id, &doc, &ReadOptions{Conflicts: true})
+	errorify(t, err)
+	if rev != resolution.Rev || len(doc.Conflicts) != 0 ||
+		(doc.Note != "mine+theirs" && doc.Note != "theirs+mine") {
+		t.Errorf("Wrong resolved doc: %v %v", rev, doc)
+	}
+	//nothing left to do
+	resolution, err = db.NewConflictResolver(KeepWinner).Resolve(id)
+	errorify(t, err)
+	if resolution != nil {
+		t.Errorf("Expected no resolution: %v", resolution)
+	}
+
+	_, err = db.SaveDesignDoc("conflicts", DesignDocument{
+		Language: "javascript",
+		Views: map[string]View{
+			"conflicted": View{
+				Map: "function(doc) { if (doc._conflicts) { emit(doc._id, null); } }",
+			},
+		},
+		Lists: map[string]string{},
+	}, "")
+	errorify(t, err)
+	createConflict(t, db, "first", "second")
+	resolutions, err := db.NewConflictResolver(LastWriteWins("updated")).
+		ResolveView("conflicts", "conflicted", nil)
+	errorify(t, err)
+	if len(resolutions) != 1 {
+		t.Errorf("Expected 1 resolution, got %v", len(resolutions))
+	}
+
+	createConflict(t, db, "third", "fourth")
+	createConflict(t, db, "fifth", "sixth")
+	resolutions, err = db.NewConflictResolver(KeepWinner).ResolveAll()
+	errorify(t, err)
+	if len(resolutions) != 2 {
+		t.Errorf("Expected 2 resolutions, got %v", len(resolutions))
+	}
+}
+
+func TestResolveLoserWithAttachment(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+
+	//the winning branch is longer, but older
+	id := getUuid()
+	rev, err := db.Save(map[string]interface{}{"updated": 1}, id, "")
+	errorify(t, err)
+	_, err = db.Save(map[string]interface{}{"updated": 2}, id, rev)
+	errorify(t, err)
+	_, err = db.bulkDocs([]map[string]interface{}{{
+		"_id":     id,
+		"_rev":    "1-00000000000000000000000000000001",
+		"updated": 3,
+		"_attachments": map[string]interface{}{
+			"note.txt": map[string]string{
+				"content_type": "text/plain",
+				"data":         base64.StdEncoding.EncodeToString([]byte("KEEP ME")),
+			},
+		},
+	}}, false)
+	errorify(t, err)
+
+	resolution, err := db.NewConflictResolver(LastWriteWins("updated")).Resolve(id)
+	errorify(t, err)
+	if resolution == nil {
+		t.Fatal("Expected a resolution")
+	}
+	att, err := db.GetAttachment(id, resolution.Rev, "text/plain", "note.txt")
+	errorify(t, err)
+	if err == nil {
+		defer att.Close()
+		data, _ := ioutil.ReadAll(att)
+		if string(data) != "KEEP ME" {
+			t.Errorf("The loser's attachment should be kept: %s", data)
+		}
+	}
+}
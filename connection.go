@@ -2,9 +2,11 @@ package couchdb
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -21,20 +23,42 @@ type connection struct {
 //processes a request
 func (conn *connection) request(method, path string,
 	body io.Reader, headers map[string]string, auth Auth) (*http.Response, error) {
+	return conn.requestContext(context.Background(), nil, method, path,
+		body, headers, auth)
+}
+
+//processes a request bound to a context, retrying as policy allows
+//(DefaultRetryPolicy if nil)
+func (conn *connection) requestContext(ctx context.Context, policy RetryPolicy,
+	method, path string, body io.Reader, headers map[string]string,
+	auth Auth) (*http.Response, error) {
+	resp, err := conn.sendContext(ctx, policy, method, path, body, headers, auth)
+	if err == nil && resp.StatusCode >= 400 {
+		return resp, parseError(resp)
+	}
+	return resp, err
+}
+
+//Like requestContext, but error responses are returned as they are,
+//for endpoints whose errors needn't be CouchDB's JSON
+func (conn *connection) sendContext(ctx context.Context, policy RetryPolicy,
+	method, path string, body io.Reader, headers map[string]string,
+	auth Auth) (*http.Response, error) {
 
 	req, err := http.NewRequest(method, conn.url+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
 	//set headers
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
-	if err != nil {
-		return nil, err
-	}
 	if auth != nil {
 		auth.AddAuthHeaders(req)
 	}
-	resp, err := conn.processResponse(0, req)
-	if err == nil && resp != nil && auth != nil {
+	resp, err := conn.processResponse(req, policy)
+	if err == nil && resp.StatusCode < 400 && auth != nil {
 		auth.UpdateAuth(resp)
 	}
 	return resp, err
@@ -73,29 +97,39 @@ func singleJoiningSlash(a, b string) string {
 	return a + b
 }
 
-func (conn *connection) processResponse(numTries int,
-	req *http.Request) (*http.Response, error) {
-	resp, err := conn.client.Do(req)
-	if err != nil {
-		errStr := err.Error()
-		// Because sometimes couchdb rudely
-		// slams the connection shut and we get a race condition.
-		// Of course, Go http presents one of two possibilities
-		// for error strings, so we check for both.
-		if (strings.Contains(errStr, "EOF") ||
-			strings.Contains(errStr, "broken connection")) && numTries < 3 {
-			//wait a bit and try again
-			fmt.Printf("\nERROR! %v\n", errStr)
-			time.Sleep(10 * time.Millisecond)
-			numTries += 1
-			return conn.processResponse(numTries, req)
-		} else {
-			return nil, err
+//sends a request, retrying as policy allows.
+//The last response is returned whatever its status.
+func (conn *connection) processResponse(req *http.Request,
+	policy RetryPolicy) (*http.Response, error) {
+	if policy == nil {
+		policy = DefaultRetryPolicy
+	}
+	for attempt := 1; ; attempt++ {
+		resp, err := conn.client.Do(req)
+		if err == nil && resp.StatusCode < 400 {
+			return resp, nil
+		}
+		delay, retry := policy.Retry(attempt, req, resp, err)
+		if !retry || !canReplay(req) || req.Context().Err() != nil {
+			if err != nil {
+				return nil, err
+			}
+			return resp, nil
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
 		}
-	} else if resp.StatusCode >= 400 {
-		return resp, parseError(resp)
-	} else {
-		return resp, nil
 	}
 }
 
@@ -217,3 +251,21 @@ func buildParamUrl(params url.Values, pathSegments ...string) (string, error) {
 	Url.RawQuery = params.Encode()
 	return Url.String(), nil
 }
+
+//Build Url with query arguments.
+//Unlike buildParamUrl, each segment is escaped as a whole,
+//so a "/" inside a segment does not start a new one.
+func buildSegmentUrl(params url.Values, pathSegments ...string) (string, error) {
+	var Url *url.URL
+	urlString := ""
+	for _, pathSegment := range pathSegments {
+		urlString += "/"
+		urlString += url.PathEscape(pathSegment)
+	}
+	Url, err := url.Parse(urlString)
+	if err != nil {
+		return "", err
+	}
+	Url.RawQuery = params.Encode()
+	return Url.String(), nil
+}
@@ -1,9 +1,12 @@
 package couchdb
 
 import (
+	"context"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 )
 
 type couchWelcome struct {
@@ -31,6 +34,18 @@ func TestUrlBuilding(t *testing.T) {
 	}
 }
 
+func TestSegmentUrlBuilding(t *testing.T) {
+	params := url.Values{}
+	params.Add("limit", "10")
+	stringified, err := buildSegmentUrl(params, "_scheduler", "docs", "other/_replicator")
+	if err != nil {
+		t.Fail()
+	}
+	if stringified != "/_scheduler/docs/other%2F_replicator?limit=10" {
+		t.Errorf("Wrong URL: %s", stringified)
+	}
+}
+
 func TestConnection(t *testing.T) {
 	client := &http.Client{}
 	c := connection{
@@ -113,3 +128,33 @@ func TestBadAuth(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestRequestContext(t *testing.T) {
+	slowServer := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(2 * time.Second):
+			case <-r.Context().Done():
+			}
+		}))
+	defer slowServer.Close()
+	conn, err := createConnection(slowServer.URL, 5*time.Second)
+	errorify(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = conn.WithContext(ctx).Ping()
+	if err == nil {
+		t.Fatal("Request should have been cancelled")
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Request was not cancelled in time: %v", time.Since(start))
+	}
+	if conn.Context() != context.Background() {
+		t.Error("The original connection should not be bound to the context")
+	}
+	db := conn.SelectDB("theDb", nil).WithContext(ctx)
+	if db.Context() != ctx {
+		t.Error("The database should be bound to the context")
+	}
+}
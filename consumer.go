@@ -0,0 +1,200 @@
+package couchdb
+
+import (
+	"sync"
+	"time"
+)
+
+//Decides whether a change whose handler failed should be retried.
+//attempt is the number of failed attempts so far, starting at 1.
+//Returns the delay before the next attempt and whether to retry at all.
+type ConsumerRetryFunc func(change *Change, attempt int, err error) (time.Duration, bool)
+
+//Called with a change that could not be handled, once retries are exhausted.
+//Returning nil skips the change; returning an error stops the consumer.
+type DeadLetterFunc func(change *Change, err error) error
+
+//Retries a failed change up to maxAttempts times in total,
+//doubling the delay after each attempt.
+func RetryChanges(maxAttempts int, delay time.Duration) ConsumerRetryFunc {
+	return func(change *Change, attempt int, err error) (time.Duration, bool) {
+		if attempt >= maxAttempts {
+			return 0, false
+		}
+		return delay << uint(attempt-1), true
+	}
+}
+
+//Consumes the changes feed of a database, handing each change to a handler.
+//The consumer checkpoints its progress in the _local/<name> document
+//and resumes from that checkpoint when it is run again.
+//Delivery is at-least-once: changes handled after the last checkpoint
+//are delivered again after a restart.
+type ChangesConsumer struct {
+	//Options for the changes feed, such as filters or IncludeDocs.
+	//Feed, Since, Limit and Timeout are managed by the consumer.
+	Options ChangesOptions
+	//Number of handled changes after which a checkpoint is written.
+	BatchSize int
+	//Maximum time a handled change may go without being checkpointed.
+	//This is also the longpoll timeout, so it must be shorter than
+	//the timeout of the connection's http Client.
+	FlushInterval time.Duration
+	//Optional.  Without a retry func, a failed change is not retried.
+	Retry ConsumerRetryFunc
+	//Optional.  Without a dead letter func, a failed change stops the consumer.
+	DeadLetter DeadLetterFunc
+	db         *Database
+	name       string
+	handler    func(change *Change) error
+	stop       chan struct{}
+	stopOnce   sync.Once
+}
+
+//The checkpoint document stored in _local/<name>
+type consumerCheckpoint struct {
+	LastSeq Seq    `json:"last_seq"`
+	Updated string `json:"updated"`
+}
+
+//Creates a consumer named name that calls handler for each change.
+func (db *Database) NewChangesConsumer(name string,
+	handler func(change *Change) error) *ChangesConsumer {
+	return &ChangesConsumer{
+		BatchSize:     100,
+		FlushInterval: 5 * time.Second,
+		db:            db,
+		name:          name,
+		handler:       handler,
+		stop:          make(chan struct{}),
+	}
+}
+
+//Consumes changes until Stop is called or an error occurs.
+//Returns nil if the consumer was stopped.
+//If the database's context is done, Run returns the context's error;
+//changes handled since the last checkpoint are delivered again next time.
+func (c *ChangesConsumer) Run() error {
+	checkpoint, err := c.readCheckpoint()
+	if err != nil {
+		return err
+	}
+	since := checkpoint.LastSeq
+	handled := 0
+	lastFlush := time.Now()
+	flush := func() error {
+		if since == checkpoint.LastSeq {
+			return nil
+		}
+		checkpoint.LastSeq = since
+		if err := c.saveCheckpoint(checkpoint); err != nil {
+			return err
+		}
+		handled = 0
+		lastFlush = time.Now()
+		return nil
+	}
+	for {
+		if c.stopped() {
+			return flush()
+		}
+		opts := c.Options
+		opts.Feed = "longpoll"
+		opts.Since = string(since)
+		opts.Limit = c.BatchSize
+		opts.Timeout = int(c.FlushInterval / time.Millisecond)
+		changes, err := c.db.Changes(&opts)
+		if err != nil {
+			flush()
+			return err
+		}
+		for i := range changes.Results {
+			change := &changes.Results[i]
+			if err := c.handle(change); err != nil {
+				flush()
+				if c.stopped() {
+					return nil
+				}
+				return err
+			}
+			since = change.Seq
+			handled++
+		}
+		//with a filter, last_seq can be ahead of the last change we saw
+		if changes.LastSeq != "" {
+			since = changes.LastSeq
+		}
+		if handled >= c.BatchSize || time.Since(lastFlush) >= c.FlushInterval {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+//runs the handler, retrying and dead lettering as configured
+func (c *ChangesConsumer) handle(change *Change) error {
+	for attempt := 1; ; attempt++ {
+		err := c.handler(change)
+		if err == nil {
+			return nil
+		}
+		if c.Retry != nil {
+			if delay, retry := c.Retry(change, attempt, err); retry {
+				select {
+				case <-time.After(delay):
+					continue
+				case <-c.stop:
+					return err
+				case <-c.db.Context().Done():
+					return c.db.Context().Err()
+				}
+			}
+		}
+		if c.DeadLetter != nil {
+			return c.DeadLetter(change, err)
+		}
+		return err
+	}
+}
+
+//Stops the consumer.
+//Run returns once the current batch is done and checkpointed.
+func (c *ChangesConsumer) Stop() {
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+func (c *ChangesConsumer) stopped() bool {
+	select {
+	case <-c.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+//Returns the sequence the consumer will resume from
+func (c *ChangesConsumer) Checkpoint() (Seq, error) {
+	checkpoint, err := c.readCheckpoint()
+	if err != nil {
+		return "", err
+	}
+	return checkpoint.LastSeq, nil
+}
+
+func (c *ChangesConsumer) readCheckpoint() (*consumerCheckpoint, error) {
+	checkpoint := consumerCheckpoint{}
+	if err := c.db.ReadLocal(c.name, &checkpoint); err != nil {
+		if couchErr, ok := err.(*Error); ok && couchErr.StatusCode == 404 {
+			//first run
+			return &checkpoint, nil
+		}
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+func (c *ChangesConsumer) saveCheckpoint(checkpoint *consumerCheckpoint) error {
+	checkpoint.Updated = time.Now().UTC().Format(time.RFC3339)
+	return c.db.SaveLocal(checkpoint, c.name)
+}
@@ -0,0 +1,75 @@
+package couchdb
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryChanges(t *testing.T) {
+	retry := RetryChanges(3, 10*time.Millisecond)
+	delay, ok := retry(&Change{}, 1, errors.New("fail"))
+	if !ok || delay != 10*time.Millisecond {
+		t.Errorf("First retry wrong: %v %v", delay, ok)
+	}
+	delay, ok = retry(&Change{}, 2, errors.New("fail"))
+	if !ok || delay != 20*time.Millisecond {
+		t.Errorf("Second retry wrong: %v %v", delay, ok)
+	}
+	if _, ok = retry(&Change{}, 3, errors.New("fail")); ok {
+		t.Error("Should give up after 3 attempts")
+	}
+}
+
+func TestChangesConsumer(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+	createLotsDocs(t, db)
+
+	seen := make(map[string]int)
+	var consumer *ChangesConsumer
+	consumer = db.NewChangesConsumer("test-consumer", func(change *Change) error {
+		seen[change.ID]++
+		if len(seen) == 10 {
+			consumer.Stop()
+		}
+		return nil
+	})
+	consumer.BatchSize = 3
+	consumer.FlushInterval = 200 * time.Millisecond
+	errorify(t, consumer.Run())
+	if len(seen) != 10 {
+		t.Errorf("Should have seen 10 docs, saw %v", len(seen))
+	}
+	checkpoint, err := consumer.Checkpoint()
+	errorify(t, err)
+	if checkpoint == "" {
+		t.Error("No checkpoint was saved")
+	}
+
+	//a new consumer with the same name picks up where the last one left off
+	id := getUuid()
+	_, err = db.Save(TestDocument{Title: "Another"}, id, "")
+	errorify(t, err)
+	failures := 0
+	var resumed *ChangesConsumer
+	resumed = db.NewChangesConsumer("test-consumer", func(change *Change) error {
+		if change.ID != id {
+			t.Errorf("Change %v should have been checkpointed", change.ID)
+		}
+		failures++
+		return errors.New("handler failure")
+	})
+	resumed.FlushInterval = 200 * time.Millisecond
+	resumed.Retry = RetryChanges(2, time.Millisecond)
+	resumed.DeadLetter = func(change *Change, err error) error {
+		resumed.Stop()
+		return nil
+	}
+	errorify(t, resumed.Run())
+	if failures != 2 {
+		t.Errorf("Handler should have been tried twice, was tried %v times", failures)
+	}
+}
@@ -3,18 +3,23 @@ package couchdb
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 )
 
-type Connection struct{ *connection }
+type Connection struct {
+	*connection
+	ctx         context.Context
+	retryPolicy RetryPolicy
+}
 
 type Database struct {
 	dbName     string
@@ -47,7 +52,7 @@ func createConnection(rawUrl string, timeout time.Duration) (*Connection, error)
 		return nil, err
 	}
 	return &Connection{
-		&connection{
+		connection: &connection{
 			url:    theUrl.String(),
 			client: &http.Client{Timeout: timeout},
 		},
@@ -55,6 +60,41 @@ func createConnection(rawUrl string, timeout time.Duration) (*Connection, error)
 
 }
 
+//Returns a copy of the connection whose requests are bound to ctx.
+//Use it for request deadlines, for cancelling long queries and streaming
+//feeds, and to pass request scoped values (e.g. for tracing) to the Transport.
+//Databases selected from the copy share its context.
+//The copy keeps the connection's retry policy.
+func (conn *Connection) WithContext(ctx context.Context) *Connection {
+	if ctx == nil {
+		panic("nil context")
+	}
+	return &Connection{connection: conn.connection, ctx: ctx,
+		retryPolicy: conn.retryPolicy}
+}
+
+//Returns the connection's context, which defaults to context.Background()
+func (conn *Connection) Context() context.Context {
+	if conn.ctx != nil {
+		return conn.ctx
+	}
+	return context.Background()
+}
+
+//processes a request bound to the connection's context
+func (conn *Connection) request(method, path string,
+	body io.Reader, headers map[string]string, auth Auth) (*http.Response, error) {
+	return conn.connection.requestContext(conn.Context(), conn.retryPolicy,
+		method, path, body, headers, auth)
+}
+
+//Like request, but error responses are returned rather than parsed
+func (conn *Connection) send(method, path string,
+	body io.Reader, headers map[string]string, auth Auth) (*http.Response, error) {
+	return conn.connection.sendContext(conn.Context(), conn.retryPolicy,
+		method, path, body, headers, auth)
+}
+
 //Use to check if database server is alive.
 func (conn *Connection) Ping() error {
 	resp, err := conn.request("HEAD", "/", nil, nil, nil)
@@ -88,6 +128,104 @@ func (conn *Connection) CreateDB(name string, auth Auth) error {
 	return err
 }
 
+//Options for creating a database.
+//Zero values leave the server defaults in place.
+type CreateDBOptions struct {
+	Shards      int  //q
+	Replicas    int  //n
+	Partitioned bool //requires CouchDB 3
+}
+
+func (opts *CreateDBOptions) values() url.Values {
+	params := url.Values{}
+	if opts == nil {
+		return params
+	}
+	if opts.Shards > 0 {
+		params.Set("q", strconv.Itoa(opts.Shards))
+	}
+	if opts.Replicas > 0 {
+		params.Set("n", strconv.Itoa(opts.Replicas))
+	}
+	if opts.Partitioned {
+		params.Set("partitioned", "true")
+	}
+	return params
+}
+
+//Databases CouchDB creates for itself, whose names start with an underscore
+var systemDBs = map[string]bool{
+	"_users":          true,
+	"_replicator":     true,
+	"_global_changes": true,
+}
+
+var dbNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_$()+/-]*$`)
+
+//Checks a database name against CouchDB's naming rules:
+//a lowercase letter followed by lowercase letters, digits or any of _$()+-/
+//System databases such as _users and _replicator are also allowed.
+func ValidateDBName(name string) error {
+	if systemDBs[name] {
+		return nil
+	}
+	//per-prefix replicator and users databases, e.g. accounting/_replicator
+	for system := range systemDBs {
+		if strings.HasSuffix(name, "/"+system) {
+			return ValidateDBName(strings.TrimSuffix(name, "/"+system))
+		}
+	}
+	if len(name) > 238 {
+		return fmt.Errorf("Database name is too long: %v", name)
+	}
+	if !dbNamePattern.MatchString(name) {
+		return fmt.Errorf("Invalid database name: %v", name)
+	}
+	return nil
+}
+
+//Create a new Database with the given shard, replica and partitioning options.
+//The name is validated before the request is sent.
+func (conn *Connection) CreateDBWithOptions(name string,
+	opts *CreateDBOptions, auth Auth) error {
+	if err := ValidateDBName(name); err != nil {
+		return err
+	}
+	url, err := buildSegmentUrl(opts.values(), name)
+	if err != nil {
+		return err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	resp, err := conn.request("PUT", url, nil, headers, auth)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return err
+}
+
+//Creates the database unless it already exists, and selects it.
+//The options only apply if the database is created.
+//Callers who aren't admins may still ensure a database they can read.
+func (conn *Connection) EnsureDB(name string, opts *CreateDBOptions,
+	auth Auth) (*Database, error) {
+	db := conn.SelectDB(name, auth)
+	err := conn.CreateDBWithOptions(name, opts, auth)
+	if isStatus(err, 412) {
+		//file_exists
+		err = nil
+	} else if isStatus(err, 401) || isStatus(err, 403) {
+		//not allowed to create databases, but it may be there already
+		if db.DbExists() == nil {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
 //Delete a Database.
 func (conn *Connection) DeleteDB(name string, auth Auth) error {
 	url, err := buildUrl(name)
@@ -166,28 +304,21 @@ func (conn *Connection) GrantRole(username string, role string,
 	auth Auth) (string, error) {
 	userDb := conn.SelectDB("_users", auth)
 	namestring := "org.couchdb.user:" + username
-	var userData interface{}
-
-	rev, err := userDb.Read(namestring, &userData, nil)
-	if err != nil {
-		return "", err
-	}
-	if reflect.ValueOf(userData).Kind() != reflect.Map {
-		return "", errors.New("Type Error")
-	}
-	userMap := userData.(map[string]interface{})
-	if reflect.ValueOf(userMap["roles"]).Kind() != reflect.Slice {
-		return "", errors.New("Type Error")
-	}
-	userRoles := userMap["roles"].([]interface{})
-	//Check if our role is already in the array, so we don't add it twice
-	for _, r := range userRoles {
-		if r == role {
-			return rev, nil
+	var userMap map[string]interface{}
+	return userDb.Update(namestring, &userMap, func() error {
+		userRoles, ok := userMap["roles"].([]interface{})
+		if !ok {
+			return errors.New("Type Error")
 		}
-	}
-	userMap["roles"] = append(userRoles, role)
-	return userDb.Save(&userMap, namestring, rev)
+		//Check if our role is already in the array, so we don't add it twice
+		for _, r := range userRoles {
+			if r == role {
+				return ErrSkipUpdate
+			}
+		}
+		userMap["roles"] = append(userRoles, role)
+		return nil
+	})
 }
 
 //Revoke a user role
@@ -196,33 +327,27 @@ func (conn *Connection) RevokeRole(username string, role string,
 
 	userDb := conn.SelectDB("_users", auth)
 	namestring := "org.couchdb.user:" + username
-	var userData interface{}
-	rev, err := userDb.Read(namestring, &userData, nil)
-	if err != nil {
-		return "", err
-	}
-	if reflect.ValueOf(userData).Kind() != reflect.Map {
-		return "", errors.New("Type Error")
-	}
-	userMap := userData.(map[string]interface{})
-	if reflect.ValueOf(userMap["roles"]).Kind() != reflect.Slice {
-		return "", errors.New("Type Error")
-	}
-	userRoles := userMap["roles"].([]interface{})
+	var userMap map[string]interface{}
 	found := false
-	for i, r := range userRoles {
-		if r == role {
-			userRoles = append(userRoles[:i], userRoles[i+1:]...)
-			found = true
-			break
+	rev, err := userDb.Update(namestring, &userMap, func() error {
+		userRoles, ok := userMap["roles"].([]interface{})
+		if !ok {
+			return errors.New("Type Error")
 		}
+		found = false
+		for i, r := range userRoles {
+			if r == role {
+				userMap["roles"] = append(userRoles[:i], userRoles[i+1:]...)
+				found = true
+				return nil
+			}
+		}
+		return ErrSkipUpdate
+	})
+	if err != nil || !found {
+		return "", err
 	}
-	userMap["roles"] = userRoles
-	if found == false {
-		return "", nil
-	} else {
-		return userDb.Save(&userMap, namestring, rev)
-	}
+	return rev, nil
 }
 
 type UserContext struct {
@@ -330,6 +455,21 @@ func (conn *Connection) SelectDB(dbName string, auth Auth) *Database {
 	}
 }
 
+//Returns a copy of the database whose requests are bound to ctx.
+//See Connection.WithContext.
+func (db *Database) WithContext(ctx context.Context) *Database {
+	return &Database{
+		dbName:     db.dbName,
+		connection: db.connection.WithContext(ctx),
+		auth:       db.auth,
+	}
+}
+
+//Returns the database's context, which defaults to context.Background()
+func (db *Database) Context() context.Context {
+	return db.connection.Context()
+}
+
 //DbExists checks if the database exists
 func (db *Database) DbExists() error {
 	resp, err := db.connection.request("HEAD", "/"+db.dbName, nil, nil, db.auth)
@@ -648,61 +788,69 @@ func (db *Database) SaveSecurity(sec Security) error {
 
 // Security helper function.
 // Adds a role to a database security doc.
+// A role added by a concurrent writer is not lost.
 func (db *Database) AddRole(role string, isAdmin bool) error {
-	sec, err := db.GetSecurity()
-	if err != nil {
-		return err
-	}
-	roles := func() *[]string {
+	return db.updateSecurity(func(sec *Security) bool {
+		roles := &sec.Members.Roles
 		if isAdmin {
-			return &sec.Admins.Roles
-		} else {
-			return &sec.Members.Roles
+			roles = &sec.Admins.Roles
 		}
-	}
-	//Make sure the role isn't already there (couchdb will let you add it twice :/ )
-	for _, r := range *roles() {
-		if r == role {
-			//already there, just return
-			return nil
+		//Make sure the role isn't already there (couchdb will let you add it twice :/ )
+		for _, r := range *roles {
+			if r == role {
+				//already there
+				return false
+			}
 		}
-	}
-	rolesarr := roles()
-	*rolesarr = append(*rolesarr, role)
-	return db.SaveSecurity(*sec)
+		*roles = append(*roles, role)
+		return true
+	})
 }
 
 // Security helper function.
 // Removes a role from a database security doc.
+// The role is removed from the members, or if it isn't a member role,
+// from the admins.
 func (db *Database) RemoveRole(role string) error {
-	sec, err := db.GetSecurity()
-	if err != nil {
-		return err
-	}
-	remove := func(isAdmin bool) bool {
-		var rolesPtr *[]string
-		if isAdmin {
-			rolesPtr = &sec.Admins.Roles
-		} else {
-			rolesPtr = &sec.Members.Roles
-		}
-		roles := *rolesPtr
-		for i, r := range roles {
-			if r == role {
-				*rolesPtr = append(roles[:i], roles[i+1:]...)
-				return true
+	var fromAdmins *bool
+	return db.updateSecurity(func(sec *Security) bool {
+		if fromAdmins == nil {
+			//decide once which list to remove it from
+			for _, isAdmin := range []bool{false, true} {
+				if indexOf(*securityRoles(sec, isAdmin), role) >= 0 {
+					isAdmin := isAdmin
+					fromAdmins = &isAdmin
+					break
+				}
+			}
+			if fromAdmins == nil {
+				return false
 			}
 		}
-		return false
-	}
-	var removed bool = false
-	if removed = remove(false); !removed {
-		removed = remove(true)
+		roles := securityRoles(sec, *fromAdmins)
+		i := indexOf(*roles, role)
+		if i < 0 {
+			return false
+		}
+		*roles = append((*roles)[:i], (*roles)[i+1:]...)
+		return true
+	})
+}
+
+func securityRoles(sec *Security, isAdmin bool) *[]string {
+	if isAdmin {
+		return &sec.Admins.Roles
 	}
-	if removed {
-		return db.SaveSecurity(*sec)
+	return &sec.Members.Roles
+}
+
+func indexOf(list []string, s string) int {
+	for i, item := range list {
+		if item == s {
+			return i
+		}
 	}
-	return nil
+	return -1
 }
 
 //Get the results of a view.
@@ -746,7 +894,6 @@ func (db *Database) GetMultipleFromView(designDoc string, view string,
 	if err != nil {
 		return err
 	}
-	fmt.Errorf("url: " + url)
 	var headers = make(map[string]string)
 	reqBody := RequestBody{Keys: keys}
 	requestBody, numBytes, err := encodeData(reqBody)
@@ -799,34 +946,29 @@ func (db *Database) GetList(designDoc string, list string,
 	return nil
 }
 
+//Parameters for a Mango query.
+//See: http://docs.couchdb.org/en/2.1.1/api/database/find.html
 type FindQueryParams struct {
-	Selector interface{} `json:"selector"`
-	Limit    int         `json:"limit,omitempty"`
-	Skip     int         `json:"skip,omitempty"`
-	Sort     interface{} `json:"sort,omitempty"`
-	Fields   []string    `json:"fields,omitempty"`
-	UseIndex interface{} `json:"user_index,omitempty"`
-}
-
+	Selector       interface{} `json:"selector"`
+	Limit          int         `json:"limit,omitempty"`
+	Skip           int         `json:"skip,omitempty"`
+	Sort           interface{} `json:"sort,omitempty"`
+	Fields         []string    `json:"fields,omitempty"`
+	UseIndex       interface{} `json:"use_index,omitempty"`
+	Bookmark       string      `json:"bookmark,omitempty"`
+	R              int         `json:"r,omitempty"`
+	Conflicts      bool        `json:"conflicts,omitempty"`
+	Update         *bool       `json:"update,omitempty"` //defaults to true
+	Stable         bool        `json:"stable,omitempty"`
+	ExecutionStats bool        `json:"execution_stats,omitempty"`
+	//Restricts the query to a single partition of a partitioned database.
+	Partition string `json:"-"`
+}
+
+//Runs a Mango query, decoding the response into results.
+//Use FindQuery for a typed response.
 func (db *Database) Find(results interface{}, params *FindQueryParams) error {
-	var err error
-	var url string
-	url, err = buildUrl(db.dbName, "_find")
-	if err != nil {
-		return err
-	}
-
-	requestBody, numBytes, err := encodeData(params)
-	if err != nil {
-		return err
-	}
-
-	var headers = make(map[string]string)
-	headers["Content-Type"] = "application/json"
-	headers["Accept"] = "application/json"
-	headers["Content-Length"] = strconv.Itoa(numBytes)
-
-	resp, err := db.connection.request("POST", url, requestBody, headers, db.auth)
+	resp, err := db.findRequest("_find", params)
 	if err != nil {
 		return err
 	}
@@ -6,7 +6,10 @@ import (
 	"github.com/twinj/uuid"
 	"io/ioutil"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -57,7 +60,7 @@ type ListResponse struct {
 	Rows      []ListResult `json:"rows,omitempty"`
 }
 
-type FindResponse struct {
+type testFindResponse struct {
 	Docs []TestDocument `json:"docs"`
 }
 
@@ -86,6 +89,34 @@ func getConnection(t *testing.T) *Connection {
 	return conn
 }
 
+//Whether the server is at least the given CouchDB version, e.g. "2.2".
+//If the version can't be read, the test goes ahead and reports the error.
+func serverAtLeast(conn *Connection, minimum string) bool {
+	info, err := conn.ServerInfo()
+	if err != nil {
+		return true
+	}
+	have := strings.Split(info.Version, ".")
+	for i, want := range strings.Split(minimum, ".") {
+		if i >= len(have) {
+			return false
+		}
+		h, _ := strconv.Atoi(strings.TrimRight(have[i], "-abcdefghijklmnopqrstuvwxyz"))
+		w, _ := strconv.Atoi(want)
+		if h != w {
+			return h > w
+		}
+	}
+	return true
+}
+
+//Skips a test of a feature the server is too old for
+func requireServer(t *testing.T, conn *Connection, minimum string) {
+	if !serverAtLeast(conn, minimum) {
+		t.Skipf("Requires CouchDB %v or later", minimum)
+	}
+}
+
 /*func getAuthConnection(t *testing.T) *Connection {
 	auth := Auth{Username: "adminuser", Password: "password"}
 	conn, err := NewConnection(server, 5984, timeout)
@@ -187,6 +218,70 @@ func TestCreateDB(t *testing.T) {
 	errorify(t, err)
 }
 
+func TestValidateDBName(t *testing.T) {
+	for _, name := range []string{"a", "test-db", "a1_$()+-/b", "_users",
+		"_replicator", "accounting/_replicator"} {
+		if err := ValidateDBName(name); err != nil {
+			t.Errorf("%v should be valid: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "Test", "1db", "_mydb", "db name",
+		"db.name", "/_users", strings.Repeat("a", 239)} {
+		if err := ValidateDBName(name); err == nil {
+			t.Errorf("%v should be invalid", name)
+		}
+	}
+}
+
+func TestEnsureDB(t *testing.T) {
+	conn := getConnection(t)
+	opts := CreateDBOptions{Shards: 2, Replicas: 1}
+	db, err := conn.EnsureDB("testensuredb", &opts, adminAuth)
+	errorify(t, err)
+	if err == nil {
+		errorify(t, db.DbExists())
+	}
+	//already there, not an error
+	_, err = conn.EnsureDB("testensuredb", &opts, adminAuth)
+	errorify(t, err)
+	err = conn.CreateDBWithOptions("testensuredb", &opts, adminAuth)
+	if couchErr, ok := err.(*Error); !ok || couchErr.StatusCode != 412 {
+		t.Errorf("Expected a 412: %v", err)
+	}
+	if err = conn.CreateDBWithOptions("Invalid", nil, adminAuth); err == nil {
+		t.Error("Invalid name should be rejected")
+	}
+	errorify(t, conn.DeleteDB("testensuredb", adminAuth))
+}
+
+func TestEnsureDBWithoutAdmin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == "PUT":
+				w.WriteHeader(401)
+				w.Write([]byte(`{"error":"unauthorized","reason":"You are not a server admin."}`))
+			case r.URL.Path == "/members-db":
+				w.WriteHeader(200)
+			default:
+				w.WriteHeader(404)
+			}
+		}))
+	defer server.Close()
+	conn, err := createConnection(server.URL, time.Second)
+	errorify(t, err)
+	//a member of an existing database
+	db, err := conn.EnsureDB("members-db", nil, nil)
+	errorify(t, err)
+	if db == nil || db.dbName != "members-db" {
+		t.Errorf("Wrong database: %v", db)
+	}
+	//the database isn't there, so the caller needed to create it
+	if _, err = conn.EnsureDB("missing-db", nil, nil); !isStatus(err, 401) {
+		t.Errorf("Expected a 401: %v", err)
+	}
+}
+
 func TestSave(t *testing.T) {
 	dbName := createTestDb(t)
 	conn := getConnection(t)
@@ -685,7 +780,7 @@ func TestFind(t *testing.T) {
 	}
 
 	//Get the results from find.
-	findResult := FindResponse{}
+	findResult := testFindResponse{}
 
 	params := FindQueryParams{Selector: &selectorObj}
 
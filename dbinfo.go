@@ -0,0 +1,164 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+//Sharding and quorum settings of a database
+type ClusterInfo struct {
+	Shards   int `json:"q"`
+	Replicas int `json:"n"`
+	W        int `json:"w"`
+	R        int `json:"r"`
+}
+
+type DatabaseProps struct {
+	Partitioned bool `json:"partitioned,omitempty"`
+}
+
+//Information about a database, as returned by GET /{db}
+type DatabaseInfo struct {
+	DBName            string        `json:"db_name"`
+	DocCount          int64         `json:"doc_count"`
+	DocDelCount       int64         `json:"doc_del_count"`
+	UpdateSeq         Seq           `json:"update_seq"`
+	PurgeSeq          Seq           `json:"purge_seq"`
+	Sizes             DatabaseSizes `json:"sizes"`
+	CompactRunning    bool          `json:"compact_running"`
+	Cluster           ClusterInfo   `json:"cluster"`
+	Props             DatabaseProps `json:"props"`
+	DiskFormatVersion int           `json:"disk_format_version"`
+	InstanceStartTime string        `json:"instance_start_time"`
+}
+
+//Returns information about the database
+func (db *Database) Info() (*DatabaseInfo, error) {
+	url, err := buildUrl(db.dbName)
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	resp, err := db.connection.request("GET", url, nil, headers, db.auth)
+	if err != nil {
+		return nil, err
+	}
+	info := DatabaseInfo{}
+	if err = parseBody(resp, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+//One database in the response to DBsInfo.
+//Info is nil and Error set if the database couldn't be read,
+//e.g. "not_found".
+type DBInfoResult struct {
+	Key   string        `json:"key"`
+	Info  *DatabaseInfo `json:"info,omitempty"`
+	Error string        `json:"error,omitempty"`
+}
+
+//Returns information about several databases in one request,
+//in the order they were named.
+func (conn *Connection) DBsInfo(names []string, auth Auth) ([]DBInfoResult, error) {
+	if len(names) == 0 {
+		return []DBInfoResult{}, nil
+	}
+	url, err := buildUrl("_dbs_info")
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Content-Type"] = "application/json"
+	headers["Accept"] = "application/json"
+	data, numBytes, err := encodeData(map[string][]string{"keys": names})
+	if err != nil {
+		return nil, err
+	}
+	headers["Content-Length"] = strconv.Itoa(numBytes)
+	if numBytes > 4000 {
+		headers["Expect"] = "100-continue"
+	}
+	resp, err := conn.request("POST", url, data, headers, auth)
+	if err != nil {
+		return nil, err
+	}
+	results := []DBInfoResult{}
+	if err = parseBody(resp, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+//Paging options for GetDBListWithOptions.
+//StartKey and EndKey are database names; EndKey is inclusive.
+type DBListOptions struct {
+	StartKey   string
+	EndKey     string
+	Limit      int
+	Skip       int
+	Descending bool
+}
+
+func (opts *DBListOptions) values() (url.Values, error) {
+	params := url.Values{}
+	if opts == nil {
+		return params, nil
+	}
+	for name, key := range map[string]string{
+		"startkey": opts.StartKey,
+		"endkey":   opts.EndKey,
+	} {
+		if key == "" {
+			continue
+		}
+		encoded, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		params.Set(name, string(encoded))
+	}
+	if opts.Limit < 0 || opts.Skip < 0 {
+		return nil, fmt.Errorf("Limit and Skip must not be negative")
+	}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Skip > 0 {
+		params.Set("skip", strconv.Itoa(opts.Skip))
+	}
+	if opts.Descending {
+		params.Set("descending", "true")
+	}
+	return params, nil
+}
+
+//Returns a page of database names.
+//To page through every database, pass the last name returned as the
+//next StartKey, with Skip set to 1.
+func (conn *Connection) GetDBListWithOptions(opts *DBListOptions,
+	auth Auth) ([]string, error) {
+	params, err := opts.values()
+	if err != nil {
+		return nil, err
+	}
+	url, err := buildParamUrl(params, "_all_dbs")
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	resp, err := conn.request("GET", url, nil, headers, auth)
+	if err != nil {
+		return nil, err
+	}
+	dbList := []string{}
+	if err = parseBody(resp, &dbList); err != nil {
+		return nil, err
+	}
+	return dbList, nil
+}
@@ -0,0 +1,84 @@
+package couchdb
+
+import (
+	"testing"
+)
+
+func TestDBListOptions(t *testing.T) {
+	opts := DBListOptions{StartKey: "a", EndKey: "b\"", Limit: 10, Skip: 1}
+	params, err := opts.values()
+	errorify(t, err)
+	if params.Get("startkey") != `"a"` || params.Get("endkey") != `"b\""` ||
+		params.Get("limit") != "10" || params.Get("skip") != "1" {
+		t.Errorf("Wrong params: %v", params)
+	}
+	opts = DBListOptions{Limit: -1}
+	if _, err := opts.values(); err == nil {
+		t.Error("Negative limit should be rejected")
+	}
+}
+
+func TestDatabaseInfo(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+	createLotsDocs(t, db)
+
+	info, err := db.Info()
+	errorify(t, err)
+	if info.DBName != dbName || info.DocCount != 10 || info.UpdateSeq == "" {
+		t.Errorf("Wrong info: %v", info)
+	}
+	t.Logf("Sizes: %v, Cluster: %v", info.Sizes, info.Cluster)
+
+	if !serverAtLeast(conn, "2.2") {
+		return
+	}
+	results, err := conn.DBsInfo([]string{dbName, "nosuchdb"}, adminAuth)
+	errorify(t, err)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %v", len(results))
+	}
+	if results[0].Key != dbName || results[0].Info == nil ||
+		results[0].Info.DocCount != 10 {
+		t.Errorf("Wrong result: %v", results[0])
+	}
+	if results[1].Info != nil || results[1].Error == "" {
+		t.Errorf("Expected an error for a missing database: %v", results[1])
+	}
+}
+
+func TestGetDBListWithOptions(t *testing.T) {
+	conn := getConnection(t)
+	names := []string{}
+	for i := 0; i < 3; i++ {
+		dbName := createTestDb(t)
+		defer deleteTestDb(t, dbName)
+		names = append(names, dbName)
+	}
+	all, err := conn.GetDBListWithOptions(&DBListOptions{
+		StartKey: unittestdb,
+		EndKey:   unittestdb + "\ufff0",
+	}, adminAuth)
+	errorify(t, err)
+	if len(all) < 3 {
+		t.Fatalf("Expected at least 3 databases, got %v", all)
+	}
+	//page through them one at a time
+	paged := []string{}
+	opts := DBListOptions{StartKey: unittestdb, EndKey: unittestdb + "\ufff0", Limit: 1}
+	for {
+		page, err := conn.GetDBListWithOptions(&opts, adminAuth)
+		errorify(t, err)
+		if err != nil || len(page) == 0 {
+			break
+		}
+		paged = append(paged, page...)
+		opts.StartKey = page[len(page)-1]
+		opts.Skip = 1
+	}
+	if len(paged) != len(all) {
+		t.Errorf("Paging returned %v, expected %v", paged, all)
+	}
+}
@@ -0,0 +1,123 @@
+package couchdb
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+//A response returned as is, for design functions that
+//may return HTML, CSV or anything else.
+//Error statuses are returned this way too, rather than as an error,
+//so check StatusCode.
+type RawResponse struct {
+	StatusCode  int
+	ContentType string
+	Header      http.Header
+	Body        []byte
+	//The revision saved by an update handler, if it saved one
+	NewRev string
+}
+
+func readRawResponse(resp *http.Response) (*RawResponse, error) {
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &RawResponse{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Header:      resp.Header,
+		Body:        body,
+		NewRev:      resp.Header.Get("X-Couch-Update-NewRev"),
+	}, nil
+}
+
+func (db *Database) rawRequest(method string, url string, body io.Reader,
+	contentType string) (*RawResponse, error) {
+	var headers = make(map[string]string)
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+	//design functions pick their own status codes and content types,
+	//so error responses are passed back too
+	resp, err := db.connection.send(method, url, body, headers, db.auth)
+	if err != nil {
+		return nil, err
+	}
+	return readRawResponse(resp)
+}
+
+func paramValues(params *url.Values) url.Values {
+	if params == nil {
+		return nil
+	}
+	return *params
+}
+
+//Calls an update handler: _design/{designDoc}/_update/{function}[/{docId}].
+//Without a docId the handler is POSTed to, and gets a null document;
+//with one, it is PUT to and gets the current document.
+//body and contentType are passed through as the request body.
+func (db *Database) CallUpdate(designDoc string, function string, docId string,
+	body io.Reader, contentType string, params *url.Values) (*RawResponse, error) {
+	segments := []string{db.dbName, "_design", designDoc, "_update", function}
+	method := "POST"
+	if docId != "" {
+		segments = append(segments, docId)
+		method = "PUT"
+	}
+	url, err := buildSegmentUrl(paramValues(params), segments...)
+	if err != nil {
+		return nil, err
+	}
+	return db.rawRequest(method, url, body, contentType)
+}
+
+//Calls a show function: _design/{designDoc}/_show/{function}[/{docId}].
+func (db *Database) CallShow(designDoc string, function string, docId string,
+	params *url.Values) (*RawResponse, error) {
+	segments := []string{db.dbName, "_design", designDoc, "_show", function}
+	if docId != "" {
+		segments = append(segments, docId)
+	}
+	url, err := buildSegmentUrl(paramValues(params), segments...)
+	if err != nil {
+		return nil, err
+	}
+	return db.rawRequest("GET", url, nil, "")
+}
+
+//Makes a request through the rewrite rules of a design document:
+//_design/{designDoc}/_rewrite/{path}.
+//path is appended as is, so any escaping is up to the caller.
+func (db *Database) Rewrite(designDoc string, method string, path string,
+	body io.Reader, contentType string, params *url.Values) (*RawResponse, error) {
+	url, err := buildSegmentUrl(paramValues(params), db.dbName, "_design",
+		designDoc, "_rewrite")
+	if err != nil {
+		return nil, err
+	}
+	if path = strings.TrimPrefix(path, "/"); path != "" {
+		if i := strings.Index(url, "?"); i >= 0 {
+			url = url[:i] + "/" + path + url[i:]
+		} else {
+			url += "/" + path
+		}
+	}
+	return db.rawRequest(method, url, body, contentType)
+}
+
+//Get the result of a list operation, whatever its content type
+func (db *Database) GetListRaw(designDoc string, list string, view string,
+	params *url.Values) (*RawResponse, error) {
+	url, err := buildSegmentUrl(paramValues(params), db.dbName, "_design",
+		designDoc, "_list", list, view)
+	if err != nil {
+		return nil, err
+	}
+	return db.rawRequest("GET", url, nil, "")
+}
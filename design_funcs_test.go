@@ -0,0 +1,162 @@
+package couchdb
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDesignFunctions(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+	createLotsDocs(t, db)
+
+	ddoc := map[string]interface{}{
+		"language": "javascript",
+		"views": map[string]View{
+			"titles": View{Map: "function(doc) { if (doc.Title) emit(doc.Title, doc.Note); }"},
+		},
+		"updates": map[string]string{
+			"note": `function(doc, req) {
+				if (!doc) {
+					doc = {_id: req.query.id, Title: "new"};
+				}
+				doc.Note = req.body;
+				return [doc, "noted " + doc._id];
+			}`,
+		},
+		"shows": map[string]string{
+			"title": `function(doc, req) {
+				return {headers: {"Content-Type": "text/html"},
+					body: "<h1>" + doc.Title + "</h1>"};
+			}`,
+		},
+		"lists": map[string]string{
+			"csv": `function(head, req) {
+				start({headers: {"Content-Type": "text/csv"}});
+				var row;
+				while (row = getRow()) {
+					send(row.key + "," + row.value + "\n");
+				}
+			}`,
+		},
+		"rewrites": []map[string]string{
+			{"from": "/titles/:id", "to": "_show/title/:id"},
+		},
+	}
+	_, err := db.SaveDesignDoc("funcs", ddoc, "")
+	errorify(t, err)
+
+	params := url.Values{}
+	params.Set("id", "noted-doc")
+	resp, err := db.CallUpdate("funcs", "note", "", strings.NewReader("chartreuse"),
+		"text/plain", &params)
+	errorify(t, err)
+	if err != nil {
+		return
+	}
+	if string(resp.Body) != "noted noted-doc" || resp.NewRev == "" {
+		t.Errorf("Wrong update response: %v %v", string(resp.Body), resp.NewRev)
+	}
+	resp, err = db.CallUpdate("funcs", "note", "noted-doc",
+		strings.NewReader("vermilion"), "text/plain", nil)
+	errorify(t, err)
+	doc := TestDocument{}
+	rev, err := db.Read("noted-doc", &doc, nil)
+	errorify(t, err)
+	if doc.Note != "vermilion" || rev != resp.NewRev {
+		t.Errorf("Update handler didn't save: %v %v", doc, rev)
+	}
+
+	resp, err = db.CallShow("funcs", "title", "noted-doc", nil)
+	errorify(t, err)
+	if string(resp.Body) != "<h1>new</h1>" ||
+		!strings.HasPrefix(resp.ContentType, "text/html") {
+		t.Errorf("Wrong show response: %v %v", resp.ContentType, string(resp.Body))
+	}
+
+	resp, err = db.Rewrite("funcs", "GET", "titles/noted-doc", nil, "", nil)
+	errorify(t, err)
+	if string(resp.Body) != "<h1>new</h1>" {
+		t.Errorf("Wrong rewrite response: %v", string(resp.Body))
+	}
+
+	params = url.Values{}
+	params.Set("limit", "3")
+	resp, err = db.GetListRaw("funcs", "csv", "titles", &params)
+	errorify(t, err)
+	lines := strings.Split(strings.TrimSpace(string(resp.Body)), "\n")
+	if !strings.HasPrefix(resp.ContentType, "text/csv") || len(lines) != 3 {
+		t.Errorf("Wrong list response: %v %v", resp.ContentType, lines)
+	}
+}
+
+func TestDesignFunctionRequests(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			requests = append(requests, r.Method+" "+r.URL.RequestURI()+" "+string(body))
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("X-Couch-Update-NewRev", "2-abc")
+			w.Write([]byte("done"))
+		}))
+	defer server.Close()
+	conn, err := createConnection(server.URL, time.Second)
+	errorify(t, err)
+	db := conn.SelectDB("db", nil)
+
+	params := url.Values{}
+	params.Set("field", "a b")
+	resp, err := db.CallUpdate("ddoc", "inc", "doc/1", strings.NewReader("x"),
+		"text/plain", &params)
+	errorify(t, err)
+	if err == nil && (resp.NewRev != "2-abc" || string(resp.Body) != "done" ||
+		resp.ContentType != "text/plain" || resp.StatusCode != 200) {
+		t.Errorf("Wrong response: %v", resp)
+	}
+	_, err = db.CallUpdate("ddoc", "inc", "", nil, "", nil)
+	errorify(t, err)
+	_, err = db.CallShow("ddoc", "page", "doc/1", nil)
+	errorify(t, err)
+	_, err = db.Rewrite("ddoc", "DELETE", "/items/1", nil, "", &params)
+	errorify(t, err)
+	_, err = db.GetListRaw("ddoc", "csv", "by_title", nil)
+	errorify(t, err)
+	expected := []string{
+		"PUT /db/_design/ddoc/_update/inc/doc%2F1?field=a+b x",
+		"POST /db/_design/ddoc/_update/inc ",
+		"GET /db/_design/ddoc/_show/page/doc%2F1 ",
+		"DELETE /db/_design/ddoc/_rewrite/items/1?field=a+b ",
+		"GET /db/_design/ddoc/_list/csv/by_title ",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Wrong requests: %v", strings.Join(requests, "\n"))
+	}
+}
+
+func TestDesignFunctionErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(404)
+			w.Write([]byte("<h1>No such page</h1>"))
+		}))
+	defer server.Close()
+	conn, err := createConnection(server.URL, time.Second)
+	errorify(t, err)
+	db := conn.SelectDB("db", nil)
+	resp, err := db.CallShow("ddoc", "page", "missing", nil)
+	errorify(t, err)
+	if err == nil && (resp.StatusCode != 404 || resp.ContentType != "text/html" ||
+		string(resp.Body) != "<h1>No such page</h1>") {
+		t.Errorf("Wrong error response: %v %v %s", resp.StatusCode,
+			resp.ContentType, resp.Body)
+	}
+}
@@ -0,0 +1,256 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+)
+
+//Options for reading a document.
+//See: http://docs.couchdb.org/en/2.1.1/api/document/common.html#get--db-docid
+type ReadOptions struct {
+	Rev              string //a specific revision, rather than the winner
+	Revs             bool   //include _revisions, the revision history
+	RevsInfo         bool   //include _revs_info, with the status of each revision
+	Conflicts        bool   //include _conflicts
+	DeletedConflicts bool   //include _deleted_conflicts
+	Latest           bool   //with Rev or open revs, return the latest leaf revisions
+	LocalSeq         bool   //include _local_seq
+	Meta             bool   //same as Conflicts, DeletedConflicts and RevsInfo
+	Attachments      bool   //include attachment bodies, not just stubs
+	AttEncodingInfo  bool   //include the encoding of compressed attachments
+}
+
+func (opts *ReadOptions) values() url.Values {
+	params := url.Values{}
+	if opts == nil {
+		return params
+	}
+	if opts.Rev != "" {
+		params.Set("rev", opts.Rev)
+	}
+	flags := map[string]bool{
+		"revs":              opts.Revs,
+		"revs_info":         opts.RevsInfo,
+		"conflicts":         opts.Conflicts,
+		"deleted_conflicts": opts.DeletedConflicts,
+		"latest":            opts.Latest,
+		"local_seq":         opts.LocalSeq,
+		"meta":              opts.Meta,
+		"attachments":       opts.Attachments,
+		"att_encoding_info": opts.AttEncodingInfo,
+	}
+	for name, set := range flags {
+		if set {
+			params.Set(name, "true")
+		}
+	}
+	return params
+}
+
+//Reads a document with the given options.
+//Pass it a &struct to hold the contents of the fetched document (doc).
+//Returns the revision read and/or error
+func (db *Database) ReadWithOptions(id string, doc interface{},
+	opts *ReadOptions) (string, error) {
+	params := opts.values()
+	return db.Read(id, doc, &params)
+}
+
+//An attachment read from a multipart response
+type OpenRevAttachment struct {
+	ContentType string
+	Data        []byte
+}
+
+//One leaf revision returned by ReadOpenRevs.
+//For a revision that doesn't exist, Missing is set and Doc is nil.
+type OpenRev struct {
+	Rev     string
+	Doc     json.RawMessage
+	Missing bool
+	//Attachments sent as separate parts of a multipart response.
+	//Their entries in the document's _attachments are stubs with "follows": true.
+	Attachments map[string]OpenRevAttachment
+}
+
+//Decodes the revision's document into v
+func (rev *OpenRev) DecodeDoc(v interface{}) error {
+	if rev.Doc == nil {
+		return fmt.Errorf("Revision %v is missing", rev.Rev)
+	}
+	return json.Unmarshal(rev.Doc, v)
+}
+
+//Reads several leaf revisions of a document in one request.
+//If revs is nil, every leaf revision is read (open_revs=all),
+//including the conflicting ones.  opts.Rev is ignored.
+func (db *Database) ReadOpenRevs(id string, revs []string,
+	opts *ReadOptions) ([]OpenRev, error) {
+	return db.readOpenRevs(id, revs, opts, "application/json")
+}
+
+//Like ReadOpenRevs, but asks for a multipart/mixed response,
+//in which attachments are sent as binary rather than base64.
+//Use it with opts.Attachments set.
+func (db *Database) ReadOpenRevsMultipart(id string, revs []string,
+	opts *ReadOptions) ([]OpenRev, error) {
+	return db.readOpenRevs(id, revs, opts, "multipart/mixed")
+}
+
+func (db *Database) readOpenRevs(id string, revs []string,
+	opts *ReadOptions, accept string) ([]OpenRev, error) {
+	params := opts.values()
+	params.Del("rev")
+	if revs == nil {
+		params.Set("open_revs", "all")
+	} else {
+		openRevs, err := json.Marshal(revs)
+		if err != nil {
+			return nil, err
+		}
+		params.Set("open_revs", string(openRevs))
+	}
+	url, err := buildParamUrl(params, db.dbName, id)
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = accept
+	resp, err := db.connection.request("GET", url, nil, headers, db.auth)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	mediaType, mediaParams, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err == nil && mediaType == "multipart/mixed" {
+		return parseOpenRevsMultipart(multipart.NewReader(resp.Body,
+			mediaParams["boundary"]))
+	}
+	return parseOpenRevsJSON(resp)
+}
+
+//reads [{"ok": doc}, {"missing": rev}, ...]
+func parseOpenRevsJSON(resp *http.Response) ([]OpenRev, error) {
+	var results []struct {
+		Ok      json.RawMessage `json:"ok"`
+		Missing string          `json:"missing"`
+	}
+	if err := parseBody(resp, &results); err != nil {
+		return nil, err
+	}
+	openRevs := []OpenRev{}
+	for _, result := range results {
+		if result.Missing != "" {
+			openRevs = append(openRevs, OpenRev{Rev: result.Missing, Missing: true})
+			continue
+		}
+		openRev, err := newOpenRev(result.Ok)
+		if err != nil {
+			return nil, err
+		}
+		openRevs = append(openRevs, *openRev)
+	}
+	return openRevs, nil
+}
+
+//Each part is either a JSON document, a JSON {"missing": rev} marked
+//with error="true", or a multipart/related holding a document
+//followed by its attachments.
+func parseOpenRevsMultipart(reader *multipart.Reader) ([]OpenRev, error) {
+	openRevs := []OpenRev{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return openRevs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		mediaType, params, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil {
+			return nil, err
+		}
+		var openRev *OpenRev
+		switch mediaType {
+		case "application/json":
+			openRev, err = readOpenRevPart(part, params["error"] == "true")
+		case "multipart/related":
+			openRev, err = readRelatedOpenRev(multipart.NewReader(part,
+				params["boundary"]))
+		default:
+			err = fmt.Errorf("Unexpected part in open_revs response: %v", mediaType)
+		}
+		if err != nil {
+			return nil, err
+		}
+		openRevs = append(openRevs, *openRev)
+	}
+}
+
+func readOpenRevPart(part *multipart.Part, isError bool) (*OpenRev, error) {
+	data, err := ioutil.ReadAll(part)
+	if err != nil {
+		return nil, err
+	}
+	if isError {
+		var missing struct {
+			Missing string `json:"missing"`
+		}
+		if err := json.Unmarshal(data, &missing); err != nil {
+			return nil, err
+		}
+		return &OpenRev{Rev: missing.Missing, Missing: true}, nil
+	}
+	return newOpenRev(data)
+}
+
+//reads a document and the attachments that follow it
+func readRelatedOpenRev(reader *multipart.Reader) (*OpenRev, error) {
+	part, err := reader.NextPart()
+	if err != nil {
+		return nil, err
+	}
+	openRev, err := readOpenRevPart(part, false)
+	if err != nil {
+		return nil, err
+	}
+	openRev.Attachments = make(map[string]OpenRevAttachment)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return openRev, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		//part.FileName() would strip everything up to the last "/"
+		_, params, err := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		openRev.Attachments[params["filename"]] = OpenRevAttachment{
+			ContentType: part.Header.Get("Content-Type"),
+			Data:        data,
+		}
+	}
+}
+
+func newOpenRev(doc json.RawMessage) (*OpenRev, error) {
+	var meta struct {
+		Rev string `json:"_rev"`
+	}
+	if err := json.Unmarshal(doc, &meta); err != nil {
+		return nil, err
+	}
+	return &OpenRev{Rev: meta.Rev, Doc: doc}, nil
+}
@@ -0,0 +1,135 @@
+package couchdb
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/textproto"
+	"testing"
+)
+
+func TestReadOptions(t *testing.T) {
+	params := (&ReadOptions{Rev: "1-abc", Revs: true, Meta: true}).values()
+	if len(params) != 3 || params.Get("rev") != "1-abc" ||
+		params.Get("revs") != "true" || params.Get("meta") != "true" {
+		t.Errorf("Wrong params: %v", params)
+	}
+}
+
+func TestParseOpenRevsMultipart(t *testing.T) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"application/json"},
+	})
+	part.Write([]byte(`{"_id":"doc","_rev":"2-a","Title":"plain"}`))
+	part, _ = writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {`application/json; error="true"`},
+	})
+	part.Write([]byte(`{"missing":"3-c"}`))
+
+	related := &bytes.Buffer{}
+	relatedWriter := multipart.NewWriter(related)
+	relatedPart, _ := relatedWriter.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"application/json"},
+	})
+	relatedPart.Write([]byte(`{"_id":"doc","_rev":"2-b","_attachments":` +
+		`{"note.txt":{"content_type":"text/plain","follows":true},` +
+		`"drafts/note.txt":{"content_type":"text/plain","follows":true}}}`))
+	relatedPart, _ = relatedWriter.CreatePart(textproto.MIMEHeader{
+		"Content-Type":        {"text/plain"},
+		"Content-Disposition": {`attachment; filename="note.txt"`},
+	})
+	relatedPart.Write([]byte("hello"))
+	relatedPart, _ = relatedWriter.CreatePart(textproto.MIMEHeader{
+		"Content-Type":        {"text/plain"},
+		"Content-Disposition": {`attachment; filename="drafts/note.txt"`},
+	})
+	relatedPart.Write([]byte("draft"))
+	relatedWriter.Close()
+	part, _ = writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/related; boundary=" + relatedWriter.Boundary()},
+	})
+	part.Write(related.Bytes())
+	writer.Close()
+
+	openRevs, err := parseOpenRevsMultipart(multipart.NewReader(body, writer.Boundary()))
+	errorify(t, err)
+	if len(openRevs) != 3 {
+		t.Fatalf("Expected 3 revisions, got %v", len(openRevs))
+	}
+	doc := TestDocument{}
+	errorify(t, openRevs[0].DecodeDoc(&doc))
+	if openRevs[0].Rev != "2-a" || doc.Title != "plain" {
+		t.Errorf("Wrong first revision: %v", openRevs[0])
+	}
+	if !openRevs[1].Missing || openRevs[1].Rev != "3-c" {
+		t.Errorf("Expected a missing revision: %v", openRevs[1])
+	}
+	attachment := openRevs[2].Attachments["note.txt"]
+	if openRevs[2].Rev != "2-b" || string(attachment.Data) != "hello" ||
+		attachment.ContentType != "text/plain" {
+		t.Errorf("Wrong attachment: %v", openRevs[2])
+	}
+	draft := openRevs[2].Attachments["drafts/note.txt"]
+	if len(openRevs[2].Attachments) != 2 || string(draft.Data) != "draft" {
+		t.Errorf("Attachment names with a slash should be kept: %v",
+			openRevs[2].Attachments)
+	}
+}
+
+func TestReadOpenRevs(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+	id := getUuid()
+	rev, err := db.Save(TestDocument{Title: "first", Note: "mine"}, id, "")
+	errorify(t, err)
+	//a conflicting revision, as replication would leave behind
+	_, err = db.bulkDocs([]map[string]interface{}{
+		{"_id": id, "_rev": "1-00000000000000000000000000000001", "Title": "other"},
+	}, false)
+	errorify(t, err)
+	attRev, err := db.SaveAttachment(id, rev, "note.txt", "text/plain",
+		bytes.NewReader([]byte("hello")))
+	errorify(t, err)
+
+	doc := struct {
+		TestDocument
+		Conflicts []string `json:"_conflicts"`
+	}{}
+	readRev, err := db.ReadWithOptions(id, &doc, &ReadOptions{Conflicts: true})
+	errorify(t, err)
+	if readRev != attRev || len(doc.Conflicts) != 1 {
+		t.Errorf("Expected one conflict: %v %v", readRev, doc.Conflicts)
+	}
+	old := TestDocument{}
+	_, err = db.ReadWithOptions(id, &old, &ReadOptions{Rev: rev})
+	errorify(t, err)
+	if old.Title != "first" {
+		t.Errorf("Wrong old revision: %v", old)
+	}
+
+	openRevs, err := db.ReadOpenRevs(id, nil, nil)
+	errorify(t, err)
+	if len(openRevs) != 2 {
+		t.Errorf("Expected 2 leaf revisions, got %v", len(openRevs))
+	}
+	openRevs, err = db.ReadOpenRevs(id, []string{attRev, "9-missing"}, nil)
+	errorify(t, err)
+	if len(openRevs) != 2 || openRevs[0].Rev != attRev || !openRevs[1].Missing {
+		t.Errorf("Wrong revisions: %v", openRevs)
+	}
+
+	openRevs, err = db.ReadOpenRevsMultipart(id, nil, &ReadOptions{Attachments: true})
+	errorify(t, err)
+	found := false
+	for _, openRev := range openRevs {
+		if openRev.Rev == attRev {
+			found = string(openRev.Attachments["note.txt"].Data) == "hello"
+		}
+	}
+	if len(openRevs) != 2 || !found {
+		t.Errorf("Expected the attachment in a multipart response: %v", openRevs)
+	}
+}
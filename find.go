@@ -0,0 +1,183 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+//The page size FindAll uses if the query has no Limit
+const findPageSize = 25
+
+//Statistics returned by a query run with ExecutionStats set
+type ExecutionStats struct {
+	TotalKeysExamined       int     `json:"total_keys_examined"`
+	TotalDocsExamined       int     `json:"total_docs_examined"`
+	TotalQuorumDocsExamined int     `json:"total_quorum_docs_examined"`
+	ResultsReturned         int     `json:"results_returned"`
+	ExecutionTimeMs         float64 `json:"execution_time_ms"`
+}
+
+//The response to a Mango query.
+//Pass Bookmark in the next query to fetch the following page.
+//Warning is set when, for instance, no index matched the query.
+type FindResponse struct {
+	Docs           []json.RawMessage `json:"docs"`
+	Bookmark       string            `json:"bookmark,omitempty"`
+	Warning        string            `json:"warning,omitempty"`
+	ExecutionStats *ExecutionStats   `json:"execution_stats,omitempty"`
+}
+
+//Decodes the docs into results, which should be a pointer to a slice
+func (r *FindResponse) DecodeDocs(results interface{}) error {
+	data, err := json.Marshal(r.Docs)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, results)
+}
+
+//Runs a Mango query and returns the typed response
+func (db *Database) FindQuery(params *FindQueryParams) (*FindResponse, error) {
+	resp, err := db.findRequest("_find", params)
+	if err != nil {
+		return nil, err
+	}
+	result := FindResponse{}
+	if err = parseBody(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+//posts the query to _find or _explain, within the partition if one is set
+func (db *Database) findRequest(endpoint string,
+	params *FindQueryParams) (*http.Response, error) {
+	if params == nil {
+		return nil, fmt.Errorf("No query specified")
+	}
+	var url string
+	var err error
+	if params.Partition != "" {
+		url, err = db.Partition(params.Partition).url(nil, endpoint)
+	} else {
+		url, err = buildUrl(db.dbName, endpoint)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Content-Type"] = "application/json"
+	headers["Accept"] = "application/json"
+	data, numBytes, err := encodeData(params)
+	if err != nil {
+		return nil, err
+	}
+	headers["Content-Length"] = strconv.Itoa(numBytes)
+	if numBytes > 4000 {
+		headers["Expect"] = "100-continue"
+	}
+	return db.connection.request("POST", url, data, headers, db.auth)
+}
+
+//Iterates over every match of a Mango query, fetching a page at a time.
+//	it := db.FindAll(&params)
+//	for it.Next() {
+//		err := it.Decode(&doc)
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type FindIterator struct {
+	db       *Database
+	params   FindQueryParams
+	page     *FindResponse
+	index    int
+	doc      json.RawMessage
+	finished bool
+	err      error
+}
+
+//Returns an iterator over every match of the query.
+//The query's Limit is used as the page size (25 if not set),
+//and its Skip only applies to the first page.
+//Paging starts from the query's Bookmark, if set.
+func (db *Database) FindAll(params *FindQueryParams) *FindIterator {
+	it := &FindIterator{db: db}
+	if params == nil {
+		it.err = fmt.Errorf("No query specified")
+		return it
+	}
+	it.params = *params
+	if it.params.Limit <= 0 {
+		it.params.Limit = findPageSize
+	}
+	return it
+}
+
+//Advances to the next document, fetching the next page when needed.
+//Returns false when every match has been read or an error occurred.
+func (it *FindIterator) Next() bool {
+	for {
+		if it.err != nil {
+			return false
+		}
+		if it.page != nil && it.index < len(it.page.Docs) {
+			it.doc = it.page.Docs[it.index]
+			it.index++
+			return true
+		}
+		it.doc = nil
+		if it.finished {
+			return false
+		}
+		page, err := it.db.FindQuery(&it.params)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page = page
+		it.index = 0
+		it.params.Skip = 0
+		it.params.Bookmark = page.Bookmark
+		//text indexes return "nil" once there are no more results
+		if len(page.Docs) < it.params.Limit || page.Bookmark == "" ||
+			page.Bookmark == "nil" {
+			it.finished = true
+		}
+	}
+}
+
+//The current document
+func (it *FindIterator) Doc() json.RawMessage {
+	return it.doc
+}
+
+//Decodes the current document into v
+func (it *FindIterator) Decode(v interface{}) error {
+	if it.doc == nil {
+		return fmt.Errorf("No current document")
+	}
+	return json.Unmarshal(it.doc, v)
+}
+
+//The bookmark returned with the last page fetched.
+//A query using it resumes after that page.
+func (it *FindIterator) Bookmark() string {
+	return it.params.Bookmark
+}
+
+//The warning returned with the last page fetched, if any
+func (it *FindIterator) Warning() string {
+	if it.page == nil {
+		return ""
+	}
+	return it.page.Warning
+}
+
+//Returns the error that stopped the iteration, if any
+func (it *FindIterator) Err() error {
+	return it.err
+}
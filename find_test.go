@@ -0,0 +1,82 @@
+package couchdb
+
+import (
+	"testing"
+)
+
+func TestFindQuery(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+	createLotsDocs(t, db)
+
+	params := FindQueryParams{
+		Selector:       map[string]interface{}{"Note": "magenta"},
+		Limit:          3,
+		ExecutionStats: true,
+	}
+	result, err := db.FindQuery(&params)
+	errorify(t, err)
+	if len(result.Docs) != 3 || result.Bookmark == "" {
+		t.Errorf("Expected 3 docs and a bookmark, got %v %v",
+			len(result.Docs), result.Bookmark)
+	}
+	//there's no index on Note
+	if result.Warning == "" {
+		t.Error("Expected a warning")
+	}
+	if result.ExecutionStats == nil || result.ExecutionStats.ResultsReturned != 3 {
+		t.Errorf("Unexpected execution stats: %v", result.ExecutionStats)
+	}
+	docs := []TestDocument{}
+	errorify(t, result.DecodeDocs(&docs))
+	for _, doc := range docs {
+		if doc.Note != "magenta" {
+			t.Errorf("Unexpected doc: %v", doc)
+		}
+	}
+
+	params.Bookmark = result.Bookmark
+	result, err = db.FindQuery(&params)
+	errorify(t, err)
+	if len(result.Docs) != 2 {
+		t.Errorf("Expected the remaining 2 docs, got %v", len(result.Docs))
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+	createLotsDocs(t, db)
+
+	//pages of 2, 2 and 1 magenta docs
+	it := db.FindAll(&FindQueryParams{
+		Selector: map[string]interface{}{"Note": "magenta"},
+		Fields:   []string{"_id", "Title", "Note"},
+		Limit:    2,
+	})
+	count := 0
+	for it.Next() {
+		doc := TestDocument{}
+		errorify(t, it.Decode(&doc))
+		if doc.Note != "magenta" {
+			t.Errorf("Unexpected doc: %v", doc)
+		}
+		count++
+	}
+	errorify(t, it.Err())
+	if count != 5 {
+		t.Errorf("Expected 5 docs, got %v", count)
+	}
+
+	it = db.FindAll(&FindQueryParams{
+		Selector: map[string]interface{}{"Note": "chartreuse"},
+	})
+	if it.Next() {
+		t.Error("Expected no docs")
+	}
+	errorify(t, it.Err())
+}
@@ -0,0 +1,158 @@
+package couchdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//The definition of a Mango index.
+//For json indexes, Fields holds field names or {"field": "asc"} objects.
+//For text indexes, Fields holds TextField values, or may be left empty
+//to index every field.
+type IndexDef struct {
+	Fields                []interface{} `json:"fields,omitempty"`
+	PartialFilterSelector interface{}   `json:"partial_filter_selector,omitempty"`
+	//text indexes only
+	DefaultField      interface{} `json:"default_field,omitempty"`
+	Analyzer          interface{} `json:"analyzer,omitempty"`
+	Selector          interface{} `json:"selector,omitempty"`
+	IndexArrayLengths *bool       `json:"index_array_lengths,omitempty"`
+}
+
+//A field of a text index.  Type is one of boolean, number or string.
+type TextField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+//Describes an index to create.
+//DDoc and Name are optional; CouchDB generates them if left empty.
+//Type defaults to json.
+type IndexRequest struct {
+	Index       IndexDef `json:"index"`
+	DDoc        string   `json:"ddoc,omitempty"`
+	Name        string   `json:"name,omitempty"`
+	Type        string   `json:"type,omitempty"`
+	Partitioned *bool    `json:"partitioned,omitempty"`
+}
+
+//The response to creating an index.  Result is "created" or "exists".
+type IndexResult struct {
+	Result string `json:"result"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+}
+
+//An index as returned by ListIndexes.
+//The special index on _id has an empty DDoc and the type "special".
+type IndexInfo struct {
+	DDoc        string   `json:"ddoc"`
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Def         IndexDef `json:"def"`
+	Partitioned bool     `json:"partitioned,omitempty"`
+}
+
+//The query plan returned by Explain
+type ExplainPlan struct {
+	DBName      string                 `json:"dbname"`
+	Index       IndexInfo              `json:"index"`
+	Partitioned interface{}            `json:"partitioned,omitempty"`
+	Selector    interface{}            `json:"selector"`
+	Opts        map[string]interface{} `json:"opts"`
+	Limit       int                    `json:"limit"`
+	Skip        int                    `json:"skip"`
+	Fields      interface{}            `json:"fields"` //"all_fields" or a list of fields
+	MRArgs      map[string]interface{} `json:"mrargs,omitempty"`
+	Covering    *bool                  `json:"covering,omitempty"`
+}
+
+//Creates a Mango index.
+//Creating an index identical to an existing one is not an error;
+//the Result is then "exists".
+func (db *Database) CreateIndex(index *IndexRequest) (*IndexResult, error) {
+	if index.Type != "" && index.Type != "json" && index.Type != "text" {
+		return nil, fmt.Errorf("Invalid index type: %v", index.Type)
+	}
+	url, err := buildUrl(db.dbName, "_index")
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Content-Type"] = "application/json"
+	headers["Accept"] = "application/json"
+	data, numBytes, err := encodeData(index)
+	if err != nil {
+		return nil, err
+	}
+	headers["Content-Length"] = strconv.Itoa(numBytes)
+	resp, err := db.connection.request("POST", url, data, headers, db.auth)
+	if err != nil {
+		return nil, err
+	}
+	result := IndexResult{}
+	if err = parseBody(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+//Lists the Mango indexes of the database, including the special _id index.
+func (db *Database) ListIndexes() ([]IndexInfo, error) {
+	url, err := buildUrl(db.dbName, "_index")
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	resp, err := db.connection.request("GET", url, nil, headers, db.auth)
+	if err != nil {
+		return nil, err
+	}
+	var indexes struct {
+		TotalRows int         `json:"total_rows"`
+		Indexes   []IndexInfo `json:"indexes"`
+	}
+	if err = parseBody(resp, &indexes); err != nil {
+		return nil, err
+	}
+	return indexes.Indexes, nil
+}
+
+//Deletes a Mango index.  The design doc may be given with or
+//without the _design/ prefix; indexType is json or text.
+func (db *Database) DeleteIndex(ddoc string, indexType string, name string) error {
+	ddoc = strings.TrimPrefix(ddoc, "_design/")
+	if ddoc == "" || name == "" {
+		return fmt.Errorf("Design doc and index name must be specified")
+	}
+	if indexType == "" {
+		indexType = "json"
+	}
+	url, err := buildSegmentUrl(nil, db.dbName, "_index", ddoc, indexType, name)
+	if err != nil {
+		return err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	resp, err := db.connection.request("DELETE", url, nil, headers, db.auth)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return err
+}
+
+//Returns the plan CouchDB would use to run a Find query,
+//including the index it picks.
+func (db *Database) Explain(params *FindQueryParams) (*ExplainPlan, error) {
+	resp, err := db.findRequest("_explain", params)
+	if err != nil {
+		return nil, err
+	}
+	plan := ExplainPlan{}
+	if err = parseBody(resp, &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
@@ -0,0 +1,77 @@
+package couchdb
+
+import (
+	"testing"
+)
+
+func TestIndexes(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+	createLotsDocs(t, db)
+
+	result, err := db.CreateIndex(&IndexRequest{
+		Index: IndexDef{Fields: []interface{}{"Note"}},
+		DDoc:  "notes",
+		Name:  "note-index",
+	})
+	errorify(t, err)
+	if result.Result != "created" || result.ID != "_design/notes" {
+		t.Errorf("Unexpected result: %v", result)
+	}
+	result, err = db.CreateIndex(&IndexRequest{
+		Index: IndexDef{Fields: []interface{}{"Note"}},
+		DDoc:  "notes",
+		Name:  "note-index",
+	})
+	errorify(t, err)
+	if result.Result != "exists" {
+		t.Errorf("Index should already exist: %v", result)
+	}
+	_, err = db.CreateIndex(&IndexRequest{
+		Index: IndexDef{
+			Fields: []interface{}{map[string]string{"Title": "asc"}},
+			PartialFilterSelector: map[string]interface{}{
+				"Note": map[string]string{"$eq": "purple"},
+			},
+		},
+		DDoc: "titles",
+		Name: "purple-titles",
+	})
+	errorify(t, err)
+
+	indexes, err := db.ListIndexes()
+	errorify(t, err)
+	//the special _id index, plus ours
+	if len(indexes) != 3 {
+		t.Errorf("Expected 3 indexes, got %v", len(indexes))
+	}
+	for _, index := range indexes {
+		t.Logf("Index: %v %v %v %v", index.DDoc, index.Name, index.Type, index.Def.Fields)
+	}
+
+	plan, err := db.Explain(&FindQueryParams{
+		Selector: map[string]interface{}{"Note": "magenta"},
+	})
+	errorify(t, err)
+	if plan.Index.Name != "note-index" || plan.Index.DDoc != "_design/notes" {
+		t.Errorf("Query should use note-index, got: %v", plan.Index)
+	}
+
+	errorify(t, db.DeleteIndex("_design/notes", "json", "note-index"))
+	errorify(t, db.DeleteIndex("titles", "", "purple-titles"))
+	indexes, err = db.ListIndexes()
+	errorify(t, err)
+	if len(indexes) != 1 || indexes[0].Type != "special" {
+		t.Errorf("Only the special index should remain: %v", indexes)
+	}
+	err = db.DeleteIndex("notes", "json", "note-index")
+	if couchErr, ok := err.(*Error); !ok || couchErr.StatusCode != 404 {
+		t.Errorf("Expected a 404: %v", err)
+	}
+	_, err = db.CreateIndex(&IndexRequest{Type: "geo"})
+	if err == nil {
+		t.Error("Invalid index type should be rejected")
+	}
+}
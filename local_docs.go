@@ -0,0 +1,159 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//_local documents are never replicated and keep no revision history,
+//so the methods here take IDs without revisions and simply overwrite
+//or delete whatever is stored.  IDs may be given with or without
+//the "_local/" prefix.
+
+//Reads a _local document into doc
+func (db *Database) ReadLocal(id string, doc interface{}) error {
+	url, err := localUrl(db.dbName, id)
+	if err != nil {
+		return err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	resp, err := db.connection.request("GET", url, nil, headers, db.auth)
+	if err != nil {
+		return err
+	}
+	return parseBody(resp, doc)
+}
+
+//Saves a _local document, replacing the stored one if there is one.
+//Any _rev in doc is ignored.
+func (db *Database) SaveLocal(doc interface{}, id string) error {
+	url, err := localUrl(db.dbName, id)
+	if err != nil {
+		return err
+	}
+	body, err := localBody(doc)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		err := db.putLocal(url, body)
+		//CouchDB before 3.0 still wants the current rev
+		if !isStatus(err, 409) || attempt >= defaultUpdateRetries {
+			return err
+		}
+		rev, err := db.localRev(id)
+		if err != nil {
+			return err
+		}
+		if rev == "" {
+			delete(body, "_rev")
+		} else {
+			body["_rev"], _ = json.Marshal(rev)
+		}
+	}
+}
+
+func (db *Database) putLocal(url string, body map[string]json.RawMessage) error {
+	var headers = make(map[string]string)
+	headers["Content-Type"] = "application/json"
+	headers["Accept"] = "application/json"
+	data, numBytes, err := encodeData(body)
+	if err != nil {
+		return err
+	}
+	headers["Content-Length"] = strconv.Itoa(numBytes)
+	if numBytes > 4000 {
+		headers["Expect"] = "100-continue"
+	}
+	resp, err := db.connection.request("PUT", url, data, headers, db.auth)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+//Deletes a _local document
+func (db *Database) DeleteLocal(id string) error {
+	url, err := localUrl(db.dbName, id)
+	if err != nil {
+		return err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	for attempt := 0; ; attempt++ {
+		resp, err := db.connection.request("DELETE", url, nil, headers, db.auth)
+		if err == nil {
+			resp.Body.Close()
+			return nil
+		}
+		if !isStatus(err, 409) || attempt >= defaultUpdateRetries {
+			return err
+		}
+		rev, err := db.localRev(id)
+		if err != nil {
+			return err
+		}
+		if rev == "" {
+			//deleted by someone else
+			return nil
+		}
+		headers["If-Match"] = rev
+	}
+}
+
+//Lists the IDs of the _local documents, without the "_local/" prefix,
+//so they can be passed straight to ReadLocal.
+//Requires CouchDB 2.2 or later.
+func (db *Database) ListLocal() ([]string, error) {
+	result, err := db.LocalDocs(nil)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, row := range result.Rows {
+		ids = append(ids, strings.TrimPrefix(row.ID, "_local/"))
+	}
+	return ids, nil
+}
+
+//the current rev of a _local document, or "" if there isn't one
+func (db *Database) localRev(id string) (string, error) {
+	var current struct {
+		Rev string `json:"_rev"`
+	}
+	if err := db.ReadLocal(id, &current); err != nil {
+		if isStatus(err, 404) {
+			return "", nil
+		}
+		return "", err
+	}
+	return current.Rev, nil
+}
+
+//The ID is a single segment, so a "/" in it is escaped
+func localUrl(dbName string, id string) (string, error) {
+	id = strings.TrimPrefix(id, "_local/")
+	if id == "" {
+		return "", fmt.Errorf("Local document ID is empty")
+	}
+	return buildSegmentUrl(nil, dbName, "_local", id)
+}
+
+//marshals doc into an object without a _rev
+func localBody(doc interface{}) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	body := make(map[string]json.RawMessage)
+	if err = json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("A local document must be a JSON object")
+	}
+	delete(body, "_rev")
+	delete(body, "_id")
+	return body, nil
+}
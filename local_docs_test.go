@@ -0,0 +1,96 @@
+package couchdb
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type nodeState struct {
+	Node  string `json:"node"`
+	Count int    `json:"count"`
+}
+
+func TestLocalDocs(t *testing.T) {
+	conn := getConnection(t)
+	requireServer(t, conn, "2.2")
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+
+	errorify(t, db.SaveLocal(nodeState{Node: "a", Count: 1}, "node/a"))
+	//no rev needed to overwrite
+	errorify(t, db.SaveLocal(nodeState{Node: "a", Count: 2}, "node/a"))
+	errorify(t, db.SaveLocal(nodeState{Node: "b", Count: 1}, "_local/node-b"))
+	state := nodeState{}
+	errorify(t, db.ReadLocal("_local/node/a", &state))
+	if state.Count != 2 {
+		t.Errorf("Wrong local doc: %v", state)
+	}
+
+	ids, err := db.ListLocal()
+	errorify(t, err)
+	if strings.Join(ids, ",") != "node-b,node/a" {
+		t.Errorf("Wrong local doc IDs: %v", ids)
+	}
+	//local docs aren't in _all_docs
+	result, err := db.AllDocs(nil)
+	errorify(t, err)
+	if len(result.Rows) != 0 {
+		t.Errorf("Local docs in _all_docs: %v", result.Rows)
+	}
+
+	errorify(t, db.DeleteLocal("node/a"))
+	err = db.ReadLocal("node/a", &state)
+	if !isStatus(err, 404) {
+		t.Errorf("Expected 404 for a deleted local doc, got %v", err)
+	}
+	if err = db.DeleteLocal("node/a"); !isStatus(err, 404) {
+		t.Errorf("Expected 404 deleting a missing local doc, got %v", err)
+	}
+}
+
+func TestLocalDocRequests(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			requests = append(requests, r.Method+" "+r.URL.EscapedPath()+" "+
+				r.Header.Get("If-Match")+string(body))
+			switch {
+			case r.Method == "GET":
+				w.Write([]byte(`{"_id":"_local/node/a","_rev":"0-3"}`))
+			case len(requests) == 1 || r.Method == "DELETE" && len(requests) == 4:
+				//an older CouchDB that checks revs
+				w.WriteHeader(409)
+				w.Write([]byte(`{"error":"conflict","reason":"Document update conflict."}`))
+			default:
+				w.WriteHeader(201)
+				w.Write([]byte(`{"ok":true,"id":"_local/node/a","rev":"0-4"}`))
+			}
+		}))
+	defer server.Close()
+	conn, err := createConnection(server.URL, time.Second)
+	errorify(t, err)
+	db := conn.SelectDB("db", nil)
+
+	errorify(t, db.SaveLocal(map[string]interface{}{"_rev": "0-1", "n": 1}, "node/a"))
+	errorify(t, db.DeleteLocal("_local/node/a"))
+	expected := []string{
+		`PUT /db/_local/node%2Fa {"n":1}`,
+		`GET /db/_local/node%2Fa `,
+		`PUT /db/_local/node%2Fa {"_rev":"0-3","n":1}`,
+		`DELETE /db/_local/node%2Fa `,
+		`GET /db/_local/node%2Fa `,
+		`DELETE /db/_local/node%2Fa 0-3`,
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Wrong requests:\n%v", strings.Join(requests, "\n"))
+	}
+	if err = db.SaveLocal([]int{1}, "node/a"); err == nil {
+		t.Error("Expected an error saving a local doc that isn't an object")
+	}
+}
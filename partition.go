@@ -0,0 +1,247 @@
+package couchdb
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+//Checks that a partition name is valid.
+//It must not be empty, start with an underscore or contain a colon.
+func ValidatePartition(partition string) error {
+	if partition == "" {
+		return fmt.Errorf("No partition specified")
+	}
+	if strings.HasPrefix(partition, "_") {
+		return fmt.Errorf("Partition names may not start with an underscore: %v",
+			partition)
+	}
+	if strings.Contains(partition, ":") {
+		return fmt.Errorf("Partition names may not contain a colon: %v", partition)
+	}
+	return nil
+}
+
+//Builds the ID of a document in a partitioned database: partition:docId
+func PartitionedID(partition string, docId string) (string, error) {
+	if err := ValidatePartition(partition); err != nil {
+		return "", err
+	}
+	if docId == "" {
+		return "", fmt.Errorf("No document ID specified")
+	}
+	return partition + ":" + docId, nil
+}
+
+//Splits the ID of a document in a partitioned database
+//into its partition and the rest of the ID.
+//Design and _local documents have no partition.
+func SplitPartitionedID(id string) (partition string, docId string, err error) {
+	i := strings.Index(id, ":")
+	if i < 0 {
+		return "", "", fmt.Errorf("Not a partitioned ID: %v", id)
+	}
+	partition, docId = id[:i], id[i+1:]
+	if err = ValidatePartition(partition); err != nil {
+		return "", "", err
+	}
+	if docId == "" {
+		return "", "", fmt.Errorf("Not a partitioned ID: %v", id)
+	}
+	return partition, docId, nil
+}
+
+//Creates a partitioned database
+func (conn *Connection) CreatePartitionedDB(name string, auth Auth) error {
+	return conn.CreateDBWithOptions(name, &CreateDBOptions{Partitioned: true}, auth)
+}
+
+//Sizes of a database or partition, in bytes
+type DatabaseSizes struct {
+	Active   int64 `json:"active"`
+	External int64 `json:"external"`
+	File     int64 `json:"file,omitempty"`
+}
+
+type PartitionInfo struct {
+	DBName      string        `json:"db_name"`
+	Partition   string        `json:"partition"`
+	DocCount    int64         `json:"doc_count"`
+	DocDelCount int64         `json:"doc_del_count"`
+	Sizes       DatabaseSizes `json:"sizes"`
+}
+
+//A single partition of a partitioned database.
+//Queries made through a Partition only see the partition's documents,
+//and document IDs are given without the partition prefix.
+type Partition struct {
+	db   *Database
+	name string
+}
+
+//Returns a handle scoped to one partition of the database.
+//The name is checked when a request is made.
+func (db *Database) Partition(name string) *Partition {
+	return &Partition{db: db, name: name}
+}
+
+func (p *Partition) Name() string {
+	return p.name
+}
+
+func (p *Partition) Database() *Database {
+	return p.db
+}
+
+//Returns the full ID of a document in this partition
+func (p *Partition) ID(docId string) (string, error) {
+	return PartitionedID(p.name, docId)
+}
+
+//builds a url below /{db}/_partition/{partition}
+func (p *Partition) url(params url.Values, pathSegments ...string) (string, error) {
+	if err := ValidatePartition(p.name); err != nil {
+		return "", err
+	}
+	segments := []string{p.db.dbName, "_partition", p.name}
+	return buildSegmentUrl(params, append(segments, pathSegments...)...)
+}
+
+//Returns the document count and sizes of the partition
+func (p *Partition) Info() (*PartitionInfo, error) {
+	url, err := p.url(nil)
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	resp, err := p.db.connection.request("GET", url, nil, headers, p.db.auth)
+	if err != nil {
+		return nil, err
+	}
+	info := PartitionInfo{}
+	if err = parseBody(resp, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+//Get the partition's _all_docs, with the usual query parameters.
+func (p *Partition) AllDocs(results interface{}, params *url.Values) error {
+	var values url.Values
+	if params != nil {
+		values = *params
+	}
+	url, err := p.url(values, "_all_docs")
+	if err != nil {
+		return err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	resp, err := p.db.connection.request("GET", url, nil, headers, p.db.auth)
+	if err != nil {
+		return err
+	}
+	return parseBody(resp, &results)
+}
+
+//Get the results of a view, limited to the partition.
+//The design doc must not set options.partitioned to false.
+func (p *Partition) GetView(designDoc string, view string,
+	results interface{}, params *url.Values) error {
+	var values url.Values
+	if params != nil {
+		values = *params
+	}
+	url, err := p.url(values, "_design", designDoc, "_view", view)
+	if err != nil {
+		return err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	resp, err := p.db.connection.request("GET", url, nil, headers, p.db.auth)
+	if err != nil {
+		return err
+	}
+	return parseBody(resp, &results)
+}
+
+//copies the query, restricting it to the partition
+func (p *Partition) query(params *FindQueryParams) (*FindQueryParams, error) {
+	if err := ValidatePartition(p.name); err != nil {
+		return nil, err
+	}
+	if params == nil {
+		return nil, fmt.Errorf("No query specified")
+	}
+	scoped := *params
+	scoped.Partition = p.name
+	return &scoped, nil
+}
+
+//Runs a Mango query against the partition.  See Database.Find.
+func (p *Partition) Find(results interface{}, params *FindQueryParams) error {
+	scoped, err := p.query(params)
+	if err != nil {
+		return err
+	}
+	return p.db.Find(results, scoped)
+}
+
+//Runs a Mango query against the partition.  See Database.FindQuery.
+func (p *Partition) FindQuery(params *FindQueryParams) (*FindResponse, error) {
+	scoped, err := p.query(params)
+	if err != nil {
+		return nil, err
+	}
+	return p.db.FindQuery(scoped)
+}
+
+//Iterates over every match in the partition.  See Database.FindAll.
+func (p *Partition) FindAll(params *FindQueryParams) *FindIterator {
+	scoped, err := p.query(params)
+	if err != nil {
+		return &FindIterator{err: err}
+	}
+	return p.db.FindAll(scoped)
+}
+
+//Explains a Mango query against the partition.  See Database.Explain.
+func (p *Partition) Explain(params *FindQueryParams) (*ExplainPlan, error) {
+	scoped, err := p.query(params)
+	if err != nil {
+		return nil, err
+	}
+	return p.db.Explain(scoped)
+}
+
+//Saves a document to the partition.
+//docId is the ID within the partition, without the prefix.
+func (p *Partition) Save(doc interface{}, docId string, rev string) (string, error) {
+	id, err := p.ID(docId)
+	if err != nil {
+		return "", err
+	}
+	return p.db.Save(doc, id, rev)
+}
+
+//Reads a document from the partition.
+//docId is the ID within the partition, without the prefix.
+func (p *Partition) Read(docId string, doc interface{},
+	params *url.Values) (string, error) {
+	id, err := p.ID(docId)
+	if err != nil {
+		return "", err
+	}
+	return p.db.Read(id, doc, params)
+}
+
+//Deletes a document from the partition.
+//docId is the ID within the partition, without the prefix.
+func (p *Partition) Delete(docId string, rev string) (string, error) {
+	id, err := p.ID(docId)
+	if err != nil {
+		return "", err
+	}
+	return p.db.Delete(id, rev)
+}
@@ -0,0 +1,117 @@
+package couchdb
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestPartitionedID(t *testing.T) {
+	id, err := PartitionedID("sensor-1", "reading-1")
+	errorify(t, err)
+	if id != "sensor-1:reading-1" {
+		t.Errorf("Wrong id: %v", id)
+	}
+	partition, docId, err := SplitPartitionedID("sensor-1:reading:1")
+	errorify(t, err)
+	if partition != "sensor-1" || docId != "reading:1" {
+		t.Errorf("Wrong split: %v %v", partition, docId)
+	}
+	for _, bad := range []string{"nocolon", ":doc", "_design:doc", "partition:"} {
+		if _, _, err := SplitPartitionedID(bad); err == nil {
+			t.Errorf("%v should be rejected", bad)
+		}
+	}
+	if _, err := PartitionedID("_sensor", "doc"); err == nil {
+		t.Error("Partitions may not start with an underscore")
+	}
+	if _, err := PartitionedID("a:b", "doc"); err == nil {
+		t.Error("Partitions may not contain a colon")
+	}
+}
+
+func TestPartition(t *testing.T) {
+	conn := getConnection(t)
+	requireServer(t, conn, "3.0")
+	dbName := unittestdb + "partitioned" + strconv.Itoa(numDbs)
+	numDbs += 1
+	errorify(t, conn.CreatePartitionedDB(dbName, adminAuth))
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+
+	red := db.Partition("red")
+	blue := db.Partition("blue")
+	for i := 0; i < 3; i++ {
+		_, err := red.Save(TestDocument{Title: "red " + strconv.Itoa(i), Note: "magenta"},
+			strconv.Itoa(i), "")
+		errorify(t, err)
+	}
+	rev, err := blue.Save(TestDocument{Title: "blue", Note: "magenta"}, "0", "")
+	errorify(t, err)
+	//a non-partitioned ID is rejected by CouchDB
+	if _, err := db.Save(TestDocument{}, getUuid(), ""); err == nil {
+		t.Error("Expected an error saving an unpartitioned ID")
+	}
+
+	doc := TestDocument{}
+	readRev, err := blue.Read("0", &doc, nil)
+	errorify(t, err)
+	if readRev != rev || doc.Title != "blue" {
+		t.Errorf("Wrong doc: %v %v", readRev, doc)
+	}
+
+	info, err := red.Info()
+	errorify(t, err)
+	if info.Partition != "red" || info.DocCount != 3 {
+		t.Errorf("Wrong info: %v", info)
+	}
+
+	var allDocs struct {
+		Rows []struct {
+			ID string `json:"id"`
+		} `json:"rows"`
+	}
+	errorify(t, red.AllDocs(&allDocs, nil))
+	if len(allDocs.Rows) != 3 || allDocs.Rows[0].ID != "red:0" {
+		t.Errorf("Wrong _all_docs: %v", allDocs.Rows)
+	}
+
+	result, err := red.FindQuery(&FindQueryParams{
+		Selector: map[string]interface{}{"Note": "magenta"},
+	})
+	errorify(t, err)
+	if len(result.Docs) != 3 {
+		t.Errorf("Expected only the red docs, got %v", len(result.Docs))
+	}
+	_, err = red.Explain(&FindQueryParams{
+		Selector: map[string]interface{}{"Note": "magenta"},
+	})
+	errorify(t, err)
+
+	designDoc := DesignDocument{
+		Language: "javascript",
+		Views: map[string]View{
+			"titles": View{Map: "function(doc) { emit(doc.Title, null); }"},
+		},
+		Lists: map[string]string{},
+	}
+	_, err = db.SaveDesignDoc("colors", designDoc, "")
+	errorify(t, err)
+	var viewResult struct {
+		Rows []struct {
+			Key string `json:"key"`
+		} `json:"rows"`
+	}
+	params := url.Values{}
+	params.Set("limit", "10")
+	errorify(t, blue.GetView("colors", "titles", &viewResult, &params))
+	if len(viewResult.Rows) != 1 || viewResult.Rows[0].Key != "blue" {
+		t.Errorf("Wrong view rows: %v", viewResult.Rows)
+	}
+
+	_, err = blue.Delete("0", rev)
+	errorify(t, err)
+	if _, err := db.Partition("_bad").Info(); err == nil {
+		t.Error("Invalid partition should be rejected")
+	}
+}
@@ -0,0 +1,284 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//The database holding persistent replication documents
+const replicatorDB = "_replicator"
+
+//A replication source or target.
+//Auth, if set, is sent to the remote server as request headers.
+type ReplicationEndpoint struct {
+	URL     string
+	Auth    Auth
+	Headers map[string]string
+}
+
+//Returns an endpoint for the database, using the database's auth.
+func (db *Database) ReplicationEndpoint() ReplicationEndpoint {
+	return ReplicationEndpoint{
+		URL:  db.connection.url + "/" + url.QueryEscape(db.dbName),
+		Auth: db.auth,
+	}
+}
+
+//Endpoints without headers are sent as a plain URL, otherwise as an object.
+func (e ReplicationEndpoint) MarshalJSON() ([]byte, error) {
+	headers := make(map[string]string)
+	for k, v := range e.Headers {
+		headers[k] = v
+	}
+	if e.Auth != nil {
+		req, err := http.NewRequest("GET", e.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		e.Auth.AddAuthHeaders(req)
+		for k := range req.Header {
+			headers[k] = req.Header.Get(k)
+		}
+	}
+	if len(headers) == 0 {
+		return json.Marshal(e.URL)
+	}
+	return json.Marshal(struct {
+		URL     string            `json:"url"`
+		Headers map[string]string `json:"headers"`
+	}{e.URL, headers})
+}
+
+func (e *ReplicationEndpoint) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &e.URL)
+	}
+	var endpoint struct {
+		URL     string            `json:"url"`
+		Headers map[string]string `json:"headers"`
+	}
+	if err := json.Unmarshal(data, &endpoint); err != nil {
+		return err
+	}
+	e.URL = endpoint.URL
+	e.Headers = endpoint.Headers
+	return nil
+}
+
+//Describes a replication.
+//See: http://docs.couchdb.org/en/2.1.1/json-structure.html#replication-settings
+type ReplicationSpec struct {
+	ID             string              `json:"_id,omitempty"`  //persistent replications only
+	Rev            string              `json:"_rev,omitempty"` //persistent replications only
+	Source         ReplicationEndpoint `json:"source"`
+	Target         ReplicationEndpoint `json:"target"`
+	Continuous     bool                `json:"continuous,omitempty"`
+	CreateTarget   bool                `json:"create_target,omitempty"`
+	DocIDs         []string            `json:"doc_ids,omitempty"`
+	Filter         string              `json:"filter,omitempty"`
+	QueryParams    map[string]string   `json:"query_params,omitempty"`
+	Selector       interface{}         `json:"selector,omitempty"`
+	SinceSeq       string              `json:"since_seq,omitempty"`
+	UseCheckpoints *bool               `json:"use_checkpoints,omitempty"` //defaults to true
+	Cancel         bool                `json:"cancel,omitempty"`          //one-shot _replicate only
+}
+
+func (spec *ReplicationSpec) validate() error {
+	if spec.Source.URL == "" || spec.Target.URL == "" {
+		return fmt.Errorf("Replication source and target must be specified")
+	}
+	filters := 0
+	if len(spec.DocIDs) > 0 {
+		filters++
+	}
+	if spec.Filter != "" {
+		filters++
+	}
+	if spec.Selector != nil {
+		filters++
+	}
+	if filters > 1 {
+		return fmt.Errorf("Only one of DocIDs, Filter and Selector may be used")
+	}
+	if len(spec.QueryParams) > 0 && spec.Filter == "" {
+		return fmt.Errorf("QueryParams require a Filter")
+	}
+	return nil
+}
+
+type ReplicationHistory struct {
+	SessionID        string `json:"session_id"`
+	StartTime        string `json:"start_time"`
+	EndTime          string `json:"end_time"`
+	StartLastSeq     Seq    `json:"start_last_seq"`
+	EndLastSeq       Seq    `json:"end_last_seq"`
+	RecordedSeq      Seq    `json:"recorded_seq"`
+	MissingChecked   int    `json:"missing_checked"`
+	MissingFound     int    `json:"missing_found"`
+	DocsRead         int    `json:"docs_read"`
+	DocsWritten      int    `json:"docs_written"`
+	DocWriteFailures int    `json:"doc_write_failures"`
+}
+
+//The response to a POST to /_replicate
+type ReplicationResult struct {
+	Ok                   bool                 `json:"ok"`
+	NoChanges            bool                 `json:"no_changes"`
+	SessionID            string               `json:"session_id"`
+	SourceLastSeq        Seq                  `json:"source_last_seq"`
+	ReplicationIDVersion int                  `json:"replication_id_version"`
+	History              []ReplicationHistory `json:"history"`
+	LocalID              string               `json:"_local_id"` //continuous replications only
+}
+
+//Runs a replication through /_replicate.
+//A one-shot replication does not return until it has finished,
+//so the connection's timeout must allow for it.
+func (conn *Connection) Replicate(spec *ReplicationSpec,
+	auth Auth) (*ReplicationResult, error) {
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	url, err := buildUrl("_replicate")
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Content-Type"] = "application/json"
+	headers["Accept"] = "application/json"
+	data, numBytes, err := encodeData(spec)
+	if err != nil {
+		return nil, err
+	}
+	headers["Content-Length"] = strconv.Itoa(numBytes)
+	resp, err := conn.request("POST", url, data, headers, auth)
+	if err != nil {
+		return nil, err
+	}
+	result := ReplicationResult{}
+	if err = parseBody(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+//Cancels a replication started through /_replicate.
+//The spec must match the one used to start the replication.
+func (conn *Connection) CancelReplication(spec *ReplicationSpec, auth Auth) error {
+	cancel := *spec
+	cancel.Cancel = true
+	_, err := conn.Replicate(&cancel, auth)
+	return err
+}
+
+//A persistent replication document from the _replicator database
+type ReplicationDoc struct {
+	ReplicationSpec
+	ReplicationID string                 `json:"_replication_id,omitempty"`
+	State         string                 `json:"_replication_state,omitempty"`
+	StateTime     string                 `json:"_replication_state_time,omitempty"`
+	StateReason   string                 `json:"_replication_state_reason,omitempty"`
+	Stats         map[string]interface{} `json:"_replication_stats,omitempty"`
+}
+
+//Starts a persistent replication by saving its document to _replicator.
+//The spec must have an ID.  Returns the revision of the document.
+func (conn *Connection) CreateReplication(spec *ReplicationSpec,
+	auth Auth) (string, error) {
+	if spec.ID == "" {
+		return "", fmt.Errorf("No ID specified")
+	}
+	if spec.Cancel {
+		return "", fmt.Errorf("Cancel is not valid for a persistent replication")
+	}
+	if err := spec.validate(); err != nil {
+		return "", err
+	}
+	return conn.SelectDB(replicatorDB, auth).Save(spec, spec.ID, "")
+}
+
+//Fetches a persistent replication document, including its state.
+func (conn *Connection) GetReplication(id string,
+	auth Auth) (*ReplicationDoc, error) {
+	doc := ReplicationDoc{}
+	rev, err := conn.SelectDB(replicatorDB, auth).Read(id, &doc, nil)
+	if err != nil {
+		return nil, err
+	}
+	doc.Rev = rev
+	return &doc, nil
+}
+
+//Cancels a persistent replication by deleting its document.
+func (conn *Connection) CancelReplicationDoc(id string, auth Auth) error {
+	doc, err := conn.GetReplication(id, auth)
+	if err != nil {
+		return err
+	}
+	_, err = conn.SelectDB(replicatorDB, auth).Delete(id, doc.Rev)
+	return err
+}
+
+//Lists the persistent replication documents in _replicator
+func (conn *Connection) ListReplications(auth Auth) ([]ReplicationDoc, error) {
+	params := url.Values{}
+	params.Set("include_docs", "true")
+	url, err := buildParamUrl(params, replicatorDB, "_all_docs")
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	resp, err := conn.request("GET", url, nil, headers, auth)
+	if err != nil {
+		return nil, err
+	}
+	var allDocs struct {
+		Rows []struct {
+			ID  string         `json:"id"`
+			Doc ReplicationDoc `json:"doc"`
+		} `json:"rows"`
+	}
+	if err = parseBody(resp, &allDocs); err != nil {
+		return nil, err
+	}
+	docs := []ReplicationDoc{}
+	for _, row := range allDocs.Rows {
+		if strings.HasPrefix(row.ID, "_design/") {
+			continue
+		}
+		docs = append(docs, row.Doc)
+	}
+	return docs, nil
+}
+
+//Polls a persistent replication until it reaches the completed or failed state,
+//checking every interval.  Returns an error if that doesn't happen within timeout.
+//Check the State of the returned document to see how the replication ended.
+func (conn *Connection) WaitForReplication(id string, interval time.Duration,
+	timeout time.Duration, auth Auth) (*ReplicationDoc, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		doc, err := conn.GetReplication(id, auth)
+		if err != nil {
+			return nil, err
+		}
+		if doc.State == "completed" || doc.State == "failed" {
+			return doc, nil
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return doc, fmt.Errorf("Timed out waiting for replication %v, state: %v",
+				id, doc.State)
+		}
+		select {
+		case <-time.After(interval):
+		case <-conn.Context().Done():
+			return doc, conn.Context().Err()
+		}
+	}
+}
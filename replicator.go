@@ -0,0 +1,424 @@
+package couchdb
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//Counters reported while a Replicator runs
+type ReplicationProgress struct {
+	MissingChecked   int
+	MissingFound     int
+	DocsRead         int
+	DocsWritten      int
+	DocWriteFailures int
+	LastSeq          Seq
+}
+
+//Replicates between two databases from the client, following the
+//CouchDB replication protocol, rather than asking a server to do it.
+//The databases may live on different connections with different auth.
+//See: http://docs.couchdb.org/en/2.1.1/replication/protocol.html
+type Replicator struct {
+	Source *Database
+	Target *Database
+	//Filters the source changes feed (DocIDs, Selector, Filter, etc.).
+	//Feed, Since, Limit, Style and Timeout are managed by the replicator.
+	Options ChangesOptions
+	//Number of changes replicated per batch
+	BatchSize int
+	//Keep replicating new changes until Stop is called
+	Continuous bool
+	//How long a continuous replication waits for new changes before
+	//asking again, which is also how long Stop may take.
+	//It is cut to half the http Client timeout of the source's
+	//connection if it isn't already shorter.
+	PollTimeout time.Duration
+	//Create the target database if it does not exist
+	CreateTarget bool
+	//Optional.  Called after every batch.
+	Progress func(progress ReplicationProgress)
+
+	bulkGetUnsupported bool
+	stop               chan struct{}
+	stopOnce           sync.Once
+}
+
+//The checkpoint stored in _local/<replication id> on both databases
+type replicationCheckpoint struct {
+	SessionID     string               `json:"session_id"`
+	SourceLastSeq Seq                  `json:"source_last_seq"`
+	History       []ReplicationHistory `json:"history"`
+}
+
+//How many sessions are kept in a checkpoint's history
+const checkpointHistorySize = 50
+
+//Creates a replicator from source to target
+func NewReplicator(source *Database, target *Database) *Replicator {
+	return &Replicator{
+		Source:      source,
+		Target:      target,
+		BatchSize:   100,
+		PollTimeout: 5 * time.Second,
+		stop:        make(chan struct{}),
+	}
+}
+
+//Stops a continuous replication after the current batch
+func (r *Replicator) Stop() {
+	r.stopOnce.Do(func() { close(r.stop) })
+}
+
+//the longpoll timeout, kept below the client timeout so that
+//an idle feed doesn't fail the request
+func (r *Replicator) pollTimeout() time.Duration {
+	timeout := r.PollTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	if clientTimeout := r.Source.connection.client.Timeout; clientTimeout > 0 &&
+		timeout >= clientTimeout {
+		timeout = clientTimeout / 2
+	}
+	if timeout < time.Millisecond {
+		timeout = time.Millisecond
+	}
+	return timeout
+}
+
+func (r *Replicator) stopped() bool {
+	select {
+	case <-r.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+//Returns the replication id, which names the checkpoint documents.
+//It depends on the source, the target and the filter options.
+func (r *Replicator) ID() (string, error) {
+	filter, err := json.Marshal(struct {
+		Filter      string
+		QueryParams url.Values
+		DocIDs      []string
+		Selector    interface{}
+		View        string
+	}{r.Options.Filter, r.Options.QueryParams, r.Options.DocIDs,
+		r.Options.Selector, r.Options.View})
+	if err != nil {
+		return "", err
+	}
+	hash := md5.New()
+	fmt.Fprintf(hash, "%v\n%v\n%s",
+		r.Source.ReplicationEndpoint().URL, r.Target.ReplicationEndpoint().URL, filter)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+//Replicates until the target has caught up with the source or,
+//for a continuous replication, until Stop is called.
+func (r *Replicator) Run() (*ReplicationProgress, error) {
+	progress := ReplicationProgress{}
+	if err := r.Source.DbExists(); err != nil {
+		return nil, err
+	}
+	if err := r.Target.DbExists(); err != nil {
+		couchErr, ok := err.(*Error)
+		if !r.CreateTarget || !ok || couchErr.StatusCode != 404 {
+			return nil, err
+		}
+		err = r.Target.connection.CreateDB(r.Target.dbName, r.Target.auth)
+		if err != nil {
+			return nil, err
+		}
+	}
+	repId, err := r.ID()
+	if err != nil {
+		return nil, err
+	}
+	sourceCheckpoint, err := readReplicationCheckpoint(r.Source, repId)
+	if err != nil {
+		return nil, err
+	}
+	targetCheckpoint, err := readReplicationCheckpoint(r.Target, repId)
+	if err != nil {
+		return nil, err
+	}
+	since := startSeq(sourceCheckpoint, targetCheckpoint)
+	sessionId, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	session := ReplicationHistory{
+		SessionID:    sessionId,
+		StartTime:    time.Now().UTC().Format(time.RFC1123),
+		StartLastSeq: since,
+	}
+	for !r.stopped() {
+		opts := r.Options
+		opts.Feed = "normal"
+		if r.Continuous {
+			opts.Feed = "longpoll"
+			opts.Timeout = int(r.pollTimeout() / time.Millisecond)
+		}
+		opts.Since = string(since)
+		opts.Style = "all_docs"
+		opts.Limit = r.BatchSize
+		opts.IncludeDocs = false
+		changes, err := r.Source.Changes(&opts)
+		if err != nil {
+			return &progress, err
+		}
+		if len(changes.Results) > 0 {
+			if err = r.replicateBatch(changes.Results, &progress); err != nil {
+				return &progress, err
+			}
+		}
+		if changes.LastSeq != "" && changes.LastSeq != since {
+			since = changes.LastSeq
+			progress.LastSeq = since
+			session.EndTime = time.Now().UTC().Format(time.RFC1123)
+			session.EndLastSeq = since
+			session.RecordedSeq = since
+			session.MissingChecked = progress.MissingChecked
+			session.MissingFound = progress.MissingFound
+			session.DocsRead = progress.DocsRead
+			session.DocsWritten = progress.DocsWritten
+			session.DocWriteFailures = progress.DocWriteFailures
+			if sourceCheckpoint, err = saveReplicationCheckpoint(r.Source, repId,
+				sourceCheckpoint, session); err != nil {
+				return &progress, err
+			}
+			if targetCheckpoint, err = saveReplicationCheckpoint(r.Target, repId,
+				targetCheckpoint, session); err != nil {
+				return &progress, err
+			}
+		}
+		if r.Progress != nil {
+			r.Progress(progress)
+		}
+		if !r.Continuous && (r.BatchSize <= 0 || len(changes.Results) < r.BatchSize) {
+			break
+		}
+	}
+	return &progress, nil
+}
+
+//copies the revisions from one batch of changes that the target is missing
+func (r *Replicator) replicateBatch(changes []Change, progress *ReplicationProgress) error {
+	revs := make(map[string][]string)
+	for _, change := range changes {
+		for _, rev := range change.Changes {
+			revs[change.ID] = append(revs[change.ID], rev.Rev)
+			progress.MissingChecked++
+		}
+	}
+	diff, err := r.Target.RevsDiff(revs)
+	if err != nil {
+		return err
+	}
+	if len(diff) == 0 {
+		return nil
+	}
+	missing := make(map[string][]string)
+	for id, result := range diff {
+		missing[id] = result.Missing
+		progress.MissingFound += len(result.Missing)
+	}
+	docs, err := r.fetchRevs(missing)
+	if err != nil {
+		return err
+	}
+	progress.DocsRead += len(docs)
+	if len(docs) == 0 {
+		return nil
+	}
+	results, err := r.Target.bulkDocs(docs, false)
+	if err != nil {
+		return err
+	}
+	failures := 0
+	for _, result := range results {
+		if result.Error != nil {
+			failures++
+		}
+	}
+	progress.DocsWritten += len(docs) - failures
+	progress.DocWriteFailures += failures
+	return nil
+}
+
+//fetches the missing revisions, with their history and attachments,
+//using _bulk_get if the source supports it and open_revs if not
+func (r *Replicator) fetchRevs(missing map[string][]string) ([]json.RawMessage, error) {
+	if !r.bulkGetUnsupported {
+		docs, err := r.Source.bulkGetRevs(missing)
+		if err == nil {
+			return docs, nil
+		}
+		couchErr, ok := err.(*Error)
+		if !ok || (couchErr.StatusCode != 400 && couchErr.StatusCode != 404 &&
+			couchErr.StatusCode != 405 && couchErr.StatusCode != 501) {
+			return nil, err
+		}
+		r.bulkGetUnsupported = true
+	}
+	docs := []json.RawMessage{}
+	for id, revs := range missing {
+		found, err := r.Source.openRevs(id, revs)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, found...)
+	}
+	return docs, nil
+}
+
+//The parameters for fetching revisions to replicate
+func replicationReadParams() url.Values {
+	params := url.Values{}
+	params.Set("revs", "true")
+	params.Set("latest", "true")
+	params.Set("attachments", "true")
+	return params
+}
+
+//POST /{db}/_bulk_get
+func (db *Database) bulkGetRevs(revs map[string][]string) ([]json.RawMessage, error) {
+	type docRef struct {
+		ID  string `json:"id"`
+		Rev string `json:"rev"`
+	}
+	refs := []docRef{}
+	for id, idRevs := range revs {
+		for _, rev := range idRevs {
+			refs = append(refs, docRef{id, rev})
+		}
+	}
+	url, err := buildParamUrl(replicationReadParams(), db.dbName, "_bulk_get")
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Content-Type"] = "application/json"
+	headers["Accept"] = "application/json"
+	data, numBytes, err := encodeData(map[string]interface{}{"docs": refs})
+	if err != nil {
+		return nil, err
+	}
+	headers["Content-Length"] = strconv.Itoa(numBytes)
+	if numBytes > 4000 {
+		headers["Expect"] = "100-continue"
+	}
+	resp, err := db.connection.request("POST", url, data, headers, db.auth)
+	if err != nil {
+		return nil, err
+	}
+	var bulkGet struct {
+		Results []struct {
+			ID   string `json:"id"`
+			Docs []struct {
+				Ok json.RawMessage `json:"ok"`
+			} `json:"docs"`
+		} `json:"results"`
+	}
+	if err = parseBody(resp, &bulkGet); err != nil {
+		return nil, err
+	}
+	docs := []json.RawMessage{}
+	for _, result := range bulkGet.Results {
+		for _, doc := range result.Docs {
+			//revisions that have gone missing in the meantime are skipped
+			if len(doc.Ok) > 0 {
+				docs = append(docs, doc.Ok)
+			}
+		}
+	}
+	return docs, nil
+}
+
+//GET /{db}/{docid}?open_revs=[...]
+func (db *Database) openRevs(id string, revs []string) ([]json.RawMessage, error) {
+	openRevs, err := db.ReadOpenRevs(id, revs,
+		&ReadOptions{Revs: true, Latest: true, Attachments: true})
+	if err != nil {
+		return nil, err
+	}
+	docs := []json.RawMessage{}
+	for _, openRev := range openRevs {
+		if !openRev.Missing {
+			docs = append(docs, openRev.Doc)
+		}
+	}
+	return docs, nil
+}
+
+func readReplicationCheckpoint(db *Database, repId string) (*replicationCheckpoint, error) {
+	checkpoint := replicationCheckpoint{}
+	if err := db.ReadLocal(repId, &checkpoint); err != nil {
+		if couchErr, ok := err.(*Error); ok && couchErr.StatusCode == 404 {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+//records the current session at the head of the checkpoint's history
+func saveReplicationCheckpoint(db *Database, repId string,
+	checkpoint *replicationCheckpoint,
+	session ReplicationHistory) (*replicationCheckpoint, error) {
+	if checkpoint == nil {
+		checkpoint = &replicationCheckpoint{}
+	}
+	history := []ReplicationHistory{session}
+	for _, entry := range checkpoint.History {
+		if entry.SessionID != session.SessionID {
+			history = append(history, entry)
+		}
+	}
+	if len(history) > checkpointHistorySize {
+		history = history[:checkpointHistorySize]
+	}
+	checkpoint.SessionID = session.SessionID
+	checkpoint.SourceLastSeq = session.RecordedSeq
+	checkpoint.History = history
+	if err := db.SaveLocal(checkpoint, repId); err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+//Finds the sequence to resume from: the last session recorded on both sides
+func startSeq(source *replicationCheckpoint, target *replicationCheckpoint) Seq {
+	if source == nil || target == nil {
+		return ""
+	}
+	if source.SessionID == target.SessionID {
+		return source.SourceLastSeq
+	}
+	for _, sourceEntry := range source.History {
+		for _, targetEntry := range target.History {
+			if sourceEntry.SessionID == targetEntry.SessionID {
+				return sourceEntry.RecordedSeq
+			}
+		}
+	}
+	return ""
+}
+
+func newSessionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
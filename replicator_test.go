@@ -0,0 +1,126 @@
+package couchdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestStartSeq(t *testing.T) {
+	source := &replicationCheckpoint{
+		SessionID:     "c",
+		SourceLastSeq: "30",
+		History: []ReplicationHistory{
+			{SessionID: "c", RecordedSeq: "30"},
+			{SessionID: "b", RecordedSeq: "20"},
+			{SessionID: "a", RecordedSeq: "10"},
+		},
+	}
+	target := &replicationCheckpoint{
+		SessionID:     "x",
+		SourceLastSeq: "25",
+		History: []ReplicationHistory{
+			{SessionID: "x", RecordedSeq: "25"},
+			{SessionID: "b", RecordedSeq: "20"},
+		},
+	}
+	if seq := startSeq(source, target); seq != "20" {
+		t.Errorf("Should resume from the common session, got %v", seq)
+	}
+	if seq := startSeq(source, source); seq != "30" {
+		t.Errorf("Should resume from the last session, got %v", seq)
+	}
+	if seq := startSeq(source, nil); seq != "" {
+		t.Errorf("Should start over without a target checkpoint, got %v", seq)
+	}
+}
+
+func TestReplicator(t *testing.T) {
+	conn := getConnection(t)
+	sourceName := createTestDb(t)
+	defer deleteTestDb(t, sourceName)
+	targetName := createTestDb(t)
+	defer deleteTestDb(t, targetName)
+	source := conn.SelectDB(sourceName, adminAuth)
+	target := conn.SelectDB(targetName, adminAuth)
+	createLotsDocs(t, source)
+	id := getUuid()
+	rev, err := source.Save(TestDocument{Title: "Attached"}, id, "")
+	errorify(t, err)
+	_, err = source.SaveAttachment(id, rev, "attachment", "text/plain",
+		bytes.NewReader([]byte("REPLICATE ME")))
+	errorify(t, err)
+
+	reported := 0
+	replicator := NewReplicator(source, target)
+	replicator.BatchSize = 4
+	replicator.Progress = func(progress ReplicationProgress) {
+		reported++
+	}
+	progress, err := replicator.Run()
+	errorify(t, err)
+	if progress.DocsWritten != 11 {
+		t.Errorf("Should have written 11 docs: %+v", progress)
+	}
+	if reported < 3 {
+		t.Errorf("Progress should be reported for every batch, was reported %v times", reported)
+	}
+	att, err := target.GetAttachment(id, "", "text/plain", "attachment")
+	errorify(t, err)
+	if err == nil {
+		defer att.Close()
+		data, _ := ioutil.ReadAll(att)
+		if string(data) != "REPLICATE ME" {
+			t.Errorf("Attachment not replicated: %s", data)
+		}
+	}
+
+	//running again resumes from the checkpoint
+	_, err = source.Save(TestDocument{Title: "Another"}, getUuid(), "")
+	errorify(t, err)
+	progress, err = NewReplicator(source, target).Run()
+	errorify(t, err)
+	if progress.MissingChecked != 1 || progress.DocsWritten != 1 {
+		t.Errorf("Should only replicate the new doc: %+v", progress)
+	}
+
+	//a continuous replication stays idle for longer than the client timeout
+	replicator = NewReplicator(source, target)
+	replicator.Continuous = true
+	done := make(chan error, 1)
+	go func() {
+		_, err := replicator.Run()
+		done <- err
+	}()
+	time.Sleep(2 * timeout)
+	lateId := getUuid()
+	_, err = source.Save(TestDocument{Title: "Late"}, lateId, "")
+	errorify(t, err)
+	time.Sleep(2 * timeout)
+	replicator.Stop()
+	errorify(t, <-done)
+	doc := TestDocument{}
+	_, err = target.Read(lateId, &doc, nil)
+	errorify(t, err)
+}
+
+func TestReplicatorPollTimeout(t *testing.T) {
+	conn, err := createConnection("http://127.0.0.1:5984", 500*time.Millisecond)
+	errorify(t, err)
+	db := conn.SelectDB("db", nil)
+	replicator := NewReplicator(db, db)
+	if timeout := replicator.pollTimeout(); timeout != 250*time.Millisecond {
+		t.Errorf("Poll timeout should be cut below the client timeout: %v", timeout)
+	}
+	replicator.PollTimeout = 100 * time.Millisecond
+	if timeout := replicator.pollTimeout(); timeout != 100*time.Millisecond {
+		t.Errorf("Wrong poll timeout: %v", timeout)
+	}
+	conn, err = createConnection("http://127.0.0.1:5984", 0)
+	errorify(t, err)
+	replicator = NewReplicator(conn.SelectDB("db", nil), db)
+	if timeout := replicator.pollTimeout(); timeout != 5*time.Second {
+		t.Errorf("Wrong default poll timeout: %v", timeout)
+	}
+}
@@ -0,0 +1,153 @@
+package couchdb
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Decides whether a failed request is tried again.
+//attempt is the number of attempts made so far, starting at 1.
+//If the request failed without a response, resp is nil and err is set;
+//otherwise err is nil and resp holds the failed (status >= 400) response.
+//Returns the delay before the next attempt and whether to retry at all.
+//Requests whose body can't be replayed are never retried.
+type RetryPolicy interface {
+	Retry(attempt int, req *http.Request, resp *http.Response, err error) (time.Duration, bool)
+}
+
+//Retries with exponential backoff and jitter.
+//The delay before retry n is BaseDelay * 2^(n-1), capped at MaxDelay,
+//of which the second half is randomized.  A longer Retry-After from the
+//server takes precedence.
+type BackoffPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	//Status codes to retry, e.g. 429, 500 and 503.
+	//Network errors such as a connection closed by the server are always retried.
+	RetryStatus []int
+	//By default only idempotent requests (GET, HEAD, PUT, DELETE, COPY, OPTIONS)
+	//are retried.  Set this to retry POSTs as well.
+	RetryNonIdempotent bool
+}
+
+//The policy used unless another is set with SetRetryPolicy.
+//Retries idempotent requests up to 3 times when CouchDB rudely slams
+//the connection shut.
+var DefaultRetryPolicy RetryPolicy = &BackoffPolicy{
+	MaxRetries: 3,
+	BaseDelay:  10 * time.Millisecond,
+	MaxDelay:   time.Second,
+}
+
+//Never retries
+var NoRetryPolicy RetryPolicy = &BackoffPolicy{}
+
+//Sets the retry policy for requests made through this Connection and the
+//databases selected from it.  Copies made by WithContext keep the policy
+//the connection had at the time, and setting a policy on a copy leaves the
+//original alone, so a request can be given its own policy like so:
+//	quick := conn.WithContext(ctx)
+//	quick.SetRetryPolicy(NoRetryPolicy)
+//Set it before sharing the Connection between goroutines.
+func (conn *Connection) SetRetryPolicy(policy RetryPolicy) {
+	conn.retryPolicy = policy
+}
+
+func (p *BackoffPolicy) Retry(attempt int, req *http.Request,
+	resp *http.Response, err error) (time.Duration, bool) {
+	if attempt > p.MaxRetries {
+		return 0, false
+	}
+	if !p.RetryNonIdempotent && !isIdempotent(req.Method) {
+		return 0, false
+	}
+	if err != nil {
+		if !isTransientError(err) {
+			return 0, false
+		}
+	} else if !p.retryStatus(resp.StatusCode) {
+		return 0, false
+	}
+	delay := p.backoff(attempt)
+	if resp != nil {
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"),
+			time.Now()); ok && retryAfter > delay {
+			delay = retryAfter
+		}
+	}
+	return delay, true
+}
+
+func (p *BackoffPolicy) retryStatus(status int) bool {
+	for _, s := range p.RetryStatus {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *BackoffPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if half := int64(delay / 2); half > 0 {
+		delay = time.Duration(half + rand.Int63n(half+1))
+	}
+	return delay
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "PUT", "DELETE", "COPY", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+//Because sometimes couchdb rudely slams the connection shut
+//and we get a race condition.  Go http presents a few possibilities
+//for error strings, so we check for all of them.
+func isTransientError(err error) bool {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	errStr := err.Error()
+	return strings.Contains(errStr, "EOF") ||
+		strings.Contains(errStr, "broken connection") ||
+		strings.Contains(errStr, "connection reset by peer")
+}
+
+//Parses a Retry-After header, which holds either seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if date.Before(now) {
+			return 0, true
+		}
+		return date.Sub(now), true
+	}
+	return 0, false
+}
+
+//Checks whether a request's body can be sent again
+func canReplay(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
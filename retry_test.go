@@ -0,0 +1,147 @@
+package couchdb
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2018, 3, 21, 12, 0, 0, 0, time.UTC)
+	if delay, ok := parseRetryAfter("3", now); !ok || delay != 3*time.Second {
+		t.Errorf("Seconds not parsed: %v %v", delay, ok)
+	}
+	date := now.Add(10 * time.Second).Format(http.TimeFormat)
+	if delay, ok := parseRetryAfter(date, now); !ok || delay != 10*time.Second {
+		t.Errorf("Date not parsed: %v %v", delay, ok)
+	}
+	if _, ok := parseRetryAfter("soon", now); ok {
+		t.Error("Garbage should not parse")
+	}
+}
+
+func TestBackoffPolicy(t *testing.T) {
+	policy := &BackoffPolicy{
+		MaxRetries:  3,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    300 * time.Millisecond,
+		RetryStatus: []int{503},
+	}
+	get, _ := http.NewRequest("GET", "http://127.0.0.1:5984/", nil)
+	post, _ := http.NewRequest("POST", "http://127.0.0.1:5984/db/_bulk_docs", nil)
+	unavailable := &http.Response{StatusCode: 503, Header: http.Header{}}
+	notFound := &http.Response{StatusCode: 404, Header: http.Header{}}
+	for attempt, max := range []time.Duration{100, 200, 300} {
+		delay, retry := policy.Retry(attempt+1, get, unavailable, nil)
+		if !retry || delay < max*time.Millisecond/2 || delay > max*time.Millisecond {
+			t.Errorf("Attempt %v: wrong delay %v", attempt+1, delay)
+		}
+	}
+	if _, retry := policy.Retry(4, get, unavailable, nil); retry {
+		t.Error("Should give up after MaxRetries")
+	}
+	if _, retry := policy.Retry(1, get, notFound, nil); retry {
+		t.Error("Should not retry a 404")
+	}
+	if _, retry := policy.Retry(1, post, unavailable, nil); retry {
+		t.Error("Should not retry a POST")
+	}
+	unavailable.Header.Set("Retry-After", "2")
+	if delay, _ := policy.Retry(1, get, unavailable, nil); delay != 2*time.Second {
+		t.Errorf("Retry-After not respected: %v", delay)
+	}
+}
+
+func TestRetryRequests(t *testing.T) {
+	failures := 0
+	bodies := []string{}
+	flakyServer := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			bodies = append(bodies, string(body))
+			if failures < 2 {
+				failures++
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(503)
+				w.Write([]byte(`{"error":"unavailable","reason":"try again"}`))
+				return
+			}
+			w.Write([]byte(`{"ok":true}`))
+		}))
+	defer flakyServer.Close()
+	conn, err := createConnection(flakyServer.URL, time.Second)
+	errorify(t, err)
+
+	//the default policy doesn't retry on status codes
+	_, err = conn.request("GET", "/", nil, nil, nil)
+	if couchErr, ok := err.(*Error); !ok || couchErr.StatusCode != 503 {
+		t.Errorf("Expected a 503: %v", err)
+	}
+
+	failures = 0
+	bodies = nil
+	conn.SetRetryPolicy(&BackoffPolicy{
+		MaxRetries:         3,
+		BaseDelay:          time.Millisecond,
+		RetryStatus:        []int{503},
+		RetryNonIdempotent: true,
+	})
+	resp, err := conn.request("POST", "/db/_find",
+		bytes.NewReader([]byte(`{"selector":{}}`)), nil, nil)
+	errorify(t, err)
+	if err == nil {
+		resp.Body.Close()
+	}
+	if len(bodies) != 3 || bodies[2] != `{"selector":{}}` {
+		t.Errorf("The body should be replayed on each attempt: %v", bodies)
+	}
+
+	//a body that can't be rewound is never retried
+	failures = 0
+	bodies = nil
+	_, err = conn.request("POST", "/db/_find",
+		ioutil.NopCloser(strings.NewReader(`{"selector":{}}`)), nil, nil)
+	if err == nil || len(bodies) != 1 {
+		t.Errorf("An unreplayable body should not be retried: %v %v", err, bodies)
+	}
+}
+
+func TestRetryPolicyPerConnection(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(503)
+			w.Write([]byte(`{"error":"unavailable","reason":"try again"}`))
+		}))
+	defer server.Close()
+	conn, err := createConnection(server.URL, time.Second)
+	errorify(t, err)
+	retrying := conn.WithContext(context.Background())
+	retrying.SetRetryPolicy(&BackoffPolicy{
+		MaxRetries:  2,
+		BaseDelay:   time.Millisecond,
+		RetryStatus: []int{503},
+	})
+
+	if _, err = conn.request("GET", "/", nil, nil, nil); !isStatus(err, 503) ||
+		requests != 1 {
+		t.Errorf("The original should keep the default policy: %v %v", err, requests)
+	}
+	requests = 0
+	db := retrying.SelectDB("db", nil)
+	if _, err = db.Info(); !isStatus(err, 503) || requests != 3 {
+		t.Errorf("The copy's databases should use its policy: %v %v", err, requests)
+	}
+	//copies keep the policy
+	requests = 0
+	if err = db.WithContext(context.Background()).DbExists(); !isStatus(err, 503) ||
+		requests != 3 {
+		t.Errorf("A copy of the copy should keep its policy: %v %v", err, requests)
+	}
+}
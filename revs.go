@@ -0,0 +1,141 @@
+package couchdb
+
+import (
+	"strconv"
+)
+
+//Revisions of documents, keyed by document ID.
+//The request body of Purge, RevsDiff and MissingRevs.
+type DocRevs map[string][]string
+
+//The outcome of a purge.
+//Purged holds the revisions that were purged, keyed by document ID.
+//PurgeSeq is only set by CouchDB before 2.3.
+type PurgeResult struct {
+	PurgeSeq Seq     `json:"purge_seq"`
+	Purged   DocRevs `json:"purged"`
+}
+
+//Which of the given revisions of a document the database is missing.
+//PossibleAncestors are revisions it has that may be their ancestors.
+type RevsDiffResult struct {
+	Missing           []string `json:"missing"`
+	PossibleAncestors []string `json:"possible_ancestors,omitempty"`
+}
+
+//Permanently removes revisions of documents, leaving no tombstone.
+//Purging a document's only leaf revision removes the whole document;
+//purges are not replicated, so purge every replica.
+//See: http://docs.couchdb.org/en/2.3.1/api/database/misc.html#db-purge
+func (db *Database) Purge(revs DocRevs) (*PurgeResult, error) {
+	result := PurgeResult{}
+	if err := db.postRevs("_purge", revs, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+//Finds the revisions that the database doesn't have.
+//Documents whose revisions are all present are left out of the result.
+func (db *Database) RevsDiff(revs DocRevs) (map[string]RevsDiffResult, error) {
+	diff := make(map[string]RevsDiffResult)
+	if err := db.postRevs("_revs_diff", revs, &diff); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+//Like RevsDiff, without the possible ancestors
+func (db *Database) MissingRevs(revs DocRevs) (DocRevs, error) {
+	var result struct {
+		MissingRevs DocRevs `json:"missing_revs"`
+	}
+	if err := db.postRevs("_missing_revs", revs, &result); err != nil {
+		return nil, err
+	}
+	if result.MissingRevs == nil {
+		result.MissingRevs = DocRevs{}
+	}
+	return result.MissingRevs, nil
+}
+
+//POST /{db}/{endpoint} with a DocRevs body
+func (db *Database) postRevs(endpoint string, revs DocRevs, result interface{}) error {
+	url, err := buildUrl(db.dbName, endpoint)
+	if err != nil {
+		return err
+	}
+	var headers = make(map[string]string)
+	headers["Content-Type"] = "application/json"
+	headers["Accept"] = "application/json"
+	data, numBytes, err := encodeData(revs)
+	if err != nil {
+		return err
+	}
+	headers["Content-Length"] = strconv.Itoa(numBytes)
+	if numBytes > 4000 {
+		headers["Expect"] = "100-continue"
+	}
+	resp, err := db.connection.request("POST", url, data, headers, db.auth)
+	if err != nil {
+		return err
+	}
+	return parseBody(resp, result)
+}
+
+//The number of purges remembered, so that indexes and
+//internal replication can catch up with them
+func (db *Database) GetPurgedInfosLimit() (int, error) {
+	return db.getLimit("_purged_infos_limit")
+}
+
+func (db *Database) SetPurgedInfosLimit(limit int) error {
+	return db.setLimit("_purged_infos_limit", limit)
+}
+
+//The number of revisions of each document remembered
+func (db *Database) GetRevsLimit() (int, error) {
+	return db.getLimit("_revs_limit")
+}
+
+func (db *Database) SetRevsLimit(limit int) error {
+	return db.setLimit("_revs_limit", limit)
+}
+
+func (db *Database) getLimit(endpoint string) (int, error) {
+	url, err := buildUrl(db.dbName, endpoint)
+	if err != nil {
+		return 0, err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	resp, err := db.connection.request("GET", url, nil, headers, db.auth)
+	if err != nil {
+		return 0, err
+	}
+	limit := 0
+	if err = parseBody(resp, &limit); err != nil {
+		return 0, err
+	}
+	return limit, nil
+}
+
+func (db *Database) setLimit(endpoint string, limit int) error {
+	url, err := buildUrl(db.dbName, endpoint)
+	if err != nil {
+		return err
+	}
+	var headers = make(map[string]string)
+	headers["Content-Type"] = "application/json"
+	headers["Accept"] = "application/json"
+	data, numBytes, err := encodeData(limit)
+	if err != nil {
+		return err
+	}
+	headers["Content-Length"] = strconv.Itoa(numBytes)
+	resp, err := db.connection.request("PUT", url, data, headers, db.auth)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+package couchdb
+
+import (
+	"testing"
+)
+
+func TestRevsAdministration(t *testing.T) {
+	conn := getConnection(t)
+	requireServer(t, conn, "2.3")
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+
+	rev1, err := db.Save(TestDocument{Title: "secret"}, "erase-me", "")
+	errorify(t, err)
+	rev2, err := db.Save(TestDocument{Title: "still secret"}, "erase-me", rev1)
+	errorify(t, err)
+	_, err = db.Save(TestDocument{Title: "keep me"}, "keep-me", "")
+	errorify(t, err)
+
+	diff, err := db.RevsDiff(DocRevs{
+		"erase-me": {rev2, "9-a"},
+		"keep-me":  {"1-b"},
+	})
+	errorify(t, err)
+	if len(diff) != 2 || len(diff["erase-me"].Missing) != 1 ||
+		diff["erase-me"].Missing[0] != "9-a" {
+		t.Errorf("Wrong revs diff: %v", diff)
+	}
+	missing, err := db.MissingRevs(DocRevs{"erase-me": {rev1, rev2, "9-a"}})
+	errorify(t, err)
+	if len(missing["erase-me"]) != 1 || missing["erase-me"][0] != "9-a" {
+		t.Errorf("Wrong missing revs: %v", missing)
+	}
+
+	result, err := db.Purge(DocRevs{"erase-me": {rev2}})
+	errorify(t, err)
+	if err == nil && (len(result.Purged["erase-me"]) != 1 ||
+		result.Purged["erase-me"][0] != rev2) {
+		t.Errorf("Wrong purge result: %v", result)
+	}
+	doc := TestDocument{}
+	if _, err = db.Read("erase-me", &doc, nil); !isStatus(err, 404) {
+		t.Errorf("Expected 404 reading a purged doc, got %v", err)
+	}
+
+	errorify(t, db.SetRevsLimit(50))
+	limit, err := db.GetRevsLimit()
+	errorify(t, err)
+	if limit != 50 {
+		t.Errorf("Wrong revs limit: %v", limit)
+	}
+	errorify(t, db.SetPurgedInfosLimit(500))
+	limit, err = db.GetPurgedInfosLimit()
+	errorify(t, err)
+	if limit != 500 {
+		t.Errorf("Wrong purged infos limit: %v", limit)
+	}
+}
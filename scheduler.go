@@ -0,0 +1,244 @@
+package couchdb
+
+import (
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//Paging for the scheduler endpoints
+type SchedulerQuery struct {
+	Limit int
+	Skip  int
+}
+
+func (q *SchedulerQuery) values() url.Values {
+	params := url.Values{}
+	if q == nil {
+		return params
+	}
+	if q.Limit > 0 {
+		params.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Skip > 0 {
+		params.Set("skip", strconv.Itoa(q.Skip))
+	}
+	return params
+}
+
+type SchedulerEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+//A replication job that is currently running or scheduled
+type SchedulerJob struct {
+	ID        string           `json:"id"`
+	Database  string           `json:"database"`
+	DocID     string           `json:"doc_id"`
+	Source    string           `json:"source"`
+	Target    string           `json:"target"`
+	User      string           `json:"user"`
+	Node      string           `json:"node"`
+	PID       string           `json:"pid"`
+	StartTime time.Time        `json:"start_time"`
+	History   []SchedulerEvent `json:"history"`
+	Info      interface{}      `json:"info"`
+}
+
+type SchedulerJobList struct {
+	TotalRows int            `json:"total_rows"`
+	Offset    int            `json:"offset"`
+	Jobs      []SchedulerJob `json:"jobs"`
+}
+
+//The scheduler's view of a replication document.
+//State is one of initializing, error, pending, running, crashing, completed or failed.
+type SchedulerDoc struct {
+	ID          string      `json:"id"` //the replication id
+	Database    string      `json:"database"`
+	DocID       string      `json:"doc_id"`
+	Node        string      `json:"node"`
+	Source      string      `json:"source"`
+	Target      string      `json:"target"`
+	State       string      `json:"state"`
+	Info        interface{} `json:"info"`
+	ErrorCount  int         `json:"error_count"`
+	LastUpdated time.Time   `json:"last_updated"`
+	StartTime   time.Time   `json:"start_time"`
+	Proxy       string      `json:"proxy,omitempty"`
+}
+
+type SchedulerDocList struct {
+	TotalRows int            `json:"total_rows"`
+	Offset    int            `json:"offset"`
+	Docs      []SchedulerDoc `json:"docs"`
+}
+
+//Lists the replication jobs known to the scheduler
+func (conn *Connection) SchedulerJobs(q *SchedulerQuery,
+	auth Auth) (*SchedulerJobList, error) {
+	url, err := buildParamUrl(q.values(), "_scheduler", "jobs")
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	resp, err := conn.request("GET", url, nil, headers, auth)
+	if err != nil {
+		return nil, err
+	}
+	jobs := SchedulerJobList{}
+	if err = parseBody(resp, &jobs); err != nil {
+		return nil, err
+	}
+	return &jobs, nil
+}
+
+//Lists the states of replication documents.
+//Leave replicatorDb empty to list documents from all replicator databases.
+//If docId is given, only that document of replicatorDb is returned.
+func (conn *Connection) SchedulerDocs(replicatorDb string, docId string,
+	q *SchedulerQuery, auth Auth) (*SchedulerDocList, error) {
+	segments := []string{"_scheduler", "docs"}
+	if replicatorDb != "" {
+		segments = append(segments, replicatorDb)
+		if docId != "" {
+			segments = append(segments, docId)
+		}
+	}
+	url, err := buildSegmentUrl(q.values(), segments...)
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	resp, err := conn.request("GET", url, nil, headers, auth)
+	if err != nil {
+		return nil, err
+	}
+	docs := SchedulerDocList{}
+	if replicatorDb != "" && docId != "" {
+		doc := SchedulerDoc{}
+		if err = parseBody(resp, &doc); err != nil {
+			return nil, err
+		}
+		docs.TotalRows = 1
+		docs.Docs = []SchedulerDoc{doc}
+		return &docs, nil
+	}
+	if err = parseBody(resp, &docs); err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+//A change in the state of a replication document.
+//From is empty for a document seen for the first time,
+//and To is empty for a document that has gone away.
+//If polling failed, only Err is set.
+type SchedulerTransition struct {
+	Doc  SchedulerDoc
+	From string
+	To   string
+	Err  error
+}
+
+//Polls _scheduler/docs and emits state transitions on C
+type SchedulerPoller struct {
+	C        <-chan SchedulerTransition
+	conn     *Connection
+	db       string
+	interval time.Duration
+	auth     Auth
+	states   map[string]SchedulerDoc
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+//The page size used when polling
+const schedulerPollLimit = 100
+
+//Starts polling the documents of replicatorDb (or of all replicator
+//databases, if empty) every interval.  Every document is reported as a
+//transition from "" on the first poll.  Call Stop when done.
+//Polling also stops when the connection's context is done.
+func (conn *Connection) NewSchedulerPoller(replicatorDb string,
+	interval time.Duration, auth Auth) *SchedulerPoller {
+	c := make(chan SchedulerTransition)
+	p := &SchedulerPoller{
+		C:        c,
+		conn:     conn,
+		db:       replicatorDb,
+		interval: interval,
+		auth:     auth,
+		states:   make(map[string]SchedulerDoc),
+		stop:     make(chan struct{}),
+	}
+	go p.run(c)
+	return p
+}
+
+//Stops polling and closes C
+func (p *SchedulerPoller) Stop() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+func (p *SchedulerPoller) run(c chan<- SchedulerTransition) {
+	defer close(c)
+	for {
+		for _, transition := range p.poll() {
+			select {
+			case c <- transition:
+			case <-p.stop:
+				return
+			case <-p.conn.Context().Done():
+				return
+			}
+		}
+		select {
+		case <-time.After(p.interval):
+		case <-p.stop:
+			return
+		case <-p.conn.Context().Done():
+			return
+		}
+	}
+}
+
+//fetches every document and compares it with the last poll
+func (p *SchedulerPoller) poll() []SchedulerTransition {
+	current := make(map[string]SchedulerDoc)
+	q := SchedulerQuery{Limit: schedulerPollLimit}
+	for {
+		docs, err := p.conn.SchedulerDocs(p.db, "", &q, p.auth)
+		if err != nil {
+			return []SchedulerTransition{{Err: err}}
+		}
+		for _, doc := range docs.Docs {
+			current[doc.Database+"/"+doc.DocID] = doc
+		}
+		q.Skip += len(docs.Docs)
+		if len(docs.Docs) < q.Limit || q.Skip >= docs.TotalRows {
+			break
+		}
+	}
+	transitions := []SchedulerTransition{}
+	for key, doc := range current {
+		previous, seen := p.states[key]
+		if !seen || previous.State != doc.State {
+			transitions = append(transitions,
+				SchedulerTransition{Doc: doc, From: previous.State, To: doc.State})
+		}
+	}
+	for key, doc := range p.states {
+		if _, ok := current[key]; !ok {
+			transitions = append(transitions,
+				SchedulerTransition{Doc: doc, From: doc.State})
+		}
+	}
+	p.states = current
+	return transitions
+}
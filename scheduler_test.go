@@ -0,0 +1,54 @@
+package couchdb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduler(t *testing.T) {
+	conn := getConnection(t)
+	sourceName := createTestDb(t)
+	defer deleteTestDb(t, sourceName)
+	targetName := createTestDb(t)
+	defer deleteTestDb(t, targetName)
+	source := conn.SelectDB(sourceName, adminAuth)
+	target := conn.SelectDB(targetName, adminAuth)
+	createLotsDocs(t, source)
+
+	poller := conn.NewSchedulerPoller("_replicator", 100*time.Millisecond, adminAuth)
+	defer poller.Stop()
+
+	id := "test-scheduler-" + getUuid()
+	_, err := conn.CreateReplication(&ReplicationSpec{
+		ID:         id,
+		Source:     source.ReplicationEndpoint(),
+		Target:     target.ReplicationEndpoint(),
+		Continuous: true,
+	}, adminAuth)
+	errorify(t, err)
+	defer conn.CancelReplicationDoc(id, adminAuth)
+
+	timeout := time.After(10 * time.Second)
+	seen := false
+	for !seen {
+		select {
+		case transition := <-poller.C:
+			errorify(t, transition.Err)
+			if transition.Doc.DocID == id && transition.To != "" {
+				t.Logf("Replication %v: %v -> %v", id, transition.From, transition.To)
+				seen = true
+			}
+		case <-timeout:
+			t.Fatal("No transition seen for the replication")
+		}
+	}
+
+	docs, err := conn.SchedulerDocs("_replicator", id, nil, adminAuth)
+	errorify(t, err)
+	if len(docs.Docs) != 1 || docs.Docs[0].DocID != id {
+		t.Errorf("Wrong scheduler docs: %v", docs)
+	}
+	jobs, err := conn.SchedulerJobs(&SchedulerQuery{Limit: 10}, adminAuth)
+	errorify(t, err)
+	t.Logf("Scheduler jobs: %v", jobs)
+}
@@ -0,0 +1,355 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+//The welcome message returned by GET /
+type ServerInfo struct {
+	CouchDB  string   `json:"couchdb"`
+	Version  string   `json:"version"`
+	GitSha   string   `json:"git_sha"`
+	UUID     string   `json:"uuid"`
+	Features []string `json:"features"`
+	Vendor   struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"vendor"`
+}
+
+//Returns the server's version, vendor and enabled features
+func (conn *Connection) ServerInfo() (*ServerInfo, error) {
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	resp, err := conn.request("GET", "/", nil, headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	info := ServerInfo{}
+	if err = parseBody(resp, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+//The status returned by /_up: "ok" when the node is ready for requests,
+//otherwise "maintenance_mode", "nolb" or "seeding".
+type UpStatus struct {
+	Status string          `json:"status"`
+	Seeds  json.RawMessage `json:"seeds,omitempty"`
+}
+
+//Whether the node is ready for requests
+func (s *UpStatus) IsUp() bool {
+	return s.Status == "ok"
+}
+
+//Checks whether the node is up, as a load balancer would.
+//A node that isn't, e.g. one in maintenance mode, is not an error;
+//its status says why.  Requires CouchDB 2.0 or later.
+func (conn *Connection) Up() (*UpStatus, error) {
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	//_up answers 404 with a status when the node is down,
+	//so the response is read whatever its status
+	resp, err := conn.send("GET", "/_up", nil, headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 && resp.StatusCode != 404 {
+		return nil, parseError(resp)
+	}
+	status := UpStatus{}
+	if err = parseBody(resp, &status); err != nil {
+		return nil, err
+	}
+	if status.Status == "" {
+		//a 404 from a server without _up
+		return nil, fmt.Errorf("No _up status from the server")
+	}
+	return &status, nil
+}
+
+//Asks the server for count new UUIDs
+func (conn *Connection) UUIDs(count int) ([]string, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("UUID count must be positive")
+	}
+	params := url.Values{}
+	params.Set("count", strconv.Itoa(count))
+	url, err := buildParamUrl(params, "_uuids")
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	resp, err := conn.request("GET", url, nil, headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		UUIDs []string `json:"uuids"`
+	}
+	if err = parseBody(resp, &result); err != nil {
+		return nil, err
+	}
+	return result.UUIDs, nil
+}
+
+//A task running on the server, as listed by /_active_tasks.
+//Depending on Type, one of Indexer, Replication or Compaction
+//holds the details; for other types, decode Raw.
+type ActiveTask struct {
+	Node      string `json:"node"`
+	PID       string `json:"pid"`
+	Type      string `json:"type"`
+	Database  string `json:"database"`
+	Progress  int    `json:"progress"`
+	StartedOn int64  `json:"started_on"`
+	UpdatedOn int64  `json:"updated_on"`
+
+	Indexer     *IndexerTask     `json:"-"`
+	Replication *ReplicationTask `json:"-"`
+	Compaction  *CompactionTask  `json:"-"`
+	Raw         json.RawMessage  `json:"-"`
+}
+
+//A view being built (type "indexer")
+type IndexerTask struct {
+	DesignDocument string `json:"design_document"`
+	ChangesDone    int64  `json:"changes_done"`
+	TotalChanges   int64  `json:"total_changes"`
+}
+
+//A running replication (type "replication")
+type ReplicationTask struct {
+	ReplicationID         string `json:"replication_id"`
+	DocID                 string `json:"doc_id"`
+	Source                string `json:"source"`
+	Target                string `json:"target"`
+	User                  string `json:"user"`
+	Continuous            bool   `json:"continuous"`
+	SourceSeq             Seq    `json:"source_seq"`
+	CheckpointedSourceSeq Seq    `json:"checkpointed_source_seq"`
+	ThroughSeq            Seq    `json:"through_seq"`
+	ChangesPending        int64  `json:"changes_pending"`
+	DocsRead              int64  `json:"docs_read"`
+	DocsWritten           int64  `json:"docs_written"`
+	DocWriteFailures      int64  `json:"doc_write_failures"`
+	MissingRevisionsFound int64  `json:"missing_revisions_found"`
+	RevisionsChecked      int64  `json:"revisions_checked"`
+	CheckpointInterval    int    `json:"checkpoint_interval"`
+}
+
+//A database or view being compacted
+//(type "database_compaction" or "view_compaction")
+type CompactionTask struct {
+	DesignDocument string `json:"design_document"` //view compaction only
+	Phase          string `json:"phase"`
+	ChangesDone    int64  `json:"changes_done"`
+	TotalChanges   int64  `json:"total_changes"`
+}
+
+func (task *ActiveTask) UnmarshalJSON(data []byte) error {
+	//an alias without the UnmarshalJSON method, to decode the common fields
+	type common ActiveTask
+	if err := json.Unmarshal(data, (*common)(task)); err != nil {
+		return err
+	}
+	task.Raw = append(json.RawMessage(nil), data...)
+	switch task.Type {
+	case "indexer":
+		task.Indexer = &IndexerTask{}
+		return json.Unmarshal(data, task.Indexer)
+	case "replication":
+		task.Replication = &ReplicationTask{}
+		return json.Unmarshal(data, task.Replication)
+	case "database_compaction", "view_compaction":
+		task.Compaction = &CompactionTask{}
+		return json.Unmarshal(data, task.Compaction)
+	}
+	return nil
+}
+
+//Lists the tasks running on the server.  Requires admin rights.
+func (conn *Connection) ActiveTasks(auth Auth) ([]ActiveTask, error) {
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	resp, err := conn.request("GET", "/_active_tasks", nil, headers, auth)
+	if err != nil {
+		return nil, err
+	}
+	tasks := []ActiveTask{}
+	if err = parseBody(resp, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+//Options for reading /_db_updates.
+//See: http://docs.couchdb.org/en/2.1.1/api/server/common.html#db-updates
+type DBUpdatesOptions struct {
+	Feed      string //normal (default), longpoll, continuous or eventsource
+	Since     string //a sequence, or "now"
+	Heartbeat int    //milliseconds
+	Timeout   int    //milliseconds
+}
+
+//A database being created, updated or deleted
+type DBUpdate struct {
+	DBName string `json:"db_name"`
+	Type   string `json:"type"` //created, updated or deleted
+	Seq    Seq    `json:"seq"`
+}
+
+type DBUpdatesResponse struct {
+	Results []DBUpdate `json:"results"`
+	LastSeq Seq        `json:"last_seq"`
+}
+
+func (opts *DBUpdatesOptions) values() url.Values {
+	params := url.Values{}
+	if opts.Feed != "" {
+		params.Set("feed", opts.Feed)
+	}
+	if opts.Since != "" {
+		params.Set("since", opts.Since)
+	}
+	if opts.Heartbeat > 0 {
+		params.Set("heartbeat", strconv.Itoa(opts.Heartbeat))
+	}
+	if opts.Timeout > 0 {
+		params.Set("timeout", strconv.Itoa(opts.Timeout))
+	}
+	return params
+}
+
+func (opts *DBUpdatesOptions) validate() error {
+	switch opts.Feed {
+	case "", "normal", "longpoll", "continuous", "eventsource":
+	default:
+		return fmt.Errorf("Invalid feed type: %v", opts.Feed)
+	}
+	if opts.Heartbeat < 0 || opts.Timeout < 0 {
+		return fmt.Errorf("Heartbeat and timeout must not be negative")
+	}
+	return nil
+}
+
+func (conn *Connection) dbUpdatesRequest(opts *DBUpdatesOptions,
+	auth Auth) (*http.Response, error) {
+	url, err := buildParamUrl(opts.values(), "_db_updates")
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	return conn.request("GET", url, nil, headers, auth)
+}
+
+//Reads /_db_updates in normal or longpoll mode.  Requires admin rights.
+//Use DBUpdatesFeed for continuous and eventsource feeds.
+func (conn *Connection) DBUpdates(opts *DBUpdatesOptions,
+	auth Auth) (*DBUpdatesResponse, error) {
+	if opts == nil {
+		opts = &DBUpdatesOptions{}
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if opts.Feed == "continuous" || opts.Feed == "eventsource" {
+		return nil, fmt.Errorf("Use DBUpdatesFeed for %v feeds", opts.Feed)
+	}
+	resp, err := conn.dbUpdatesRequest(opts, auth)
+	if err != nil {
+		return nil, err
+	}
+	updates := DBUpdatesResponse{}
+	if err = parseBody(resp, &updates); err != nil {
+		return nil, err
+	}
+	return &updates, nil
+}
+
+//Streams updates from a continuous or eventsource /_db_updates feed.
+//If the connection drops, the feed reconnects from the last seen sequence,
+//and keeps trying while the server is unreachable.  Unless Timeout is set,
+//the feed also reconnects when the server ends it.
+//It is used in the same way as ChangesFeed.
+type DBUpdatesFeed struct {
+	conn    *Connection
+	auth    Auth
+	options DBUpdatesOptions
+	stream  *feedStream
+	update  *DBUpdate
+}
+
+//Opens a continuous (default) or eventsource /_db_updates feed.
+//Requires admin rights.  The caller must Close the feed when done with it.
+func (conn *Connection) DBUpdatesFeed(opts *DBUpdatesOptions,
+	auth Auth) (*DBUpdatesFeed, error) {
+	var options DBUpdatesOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.Feed == "" {
+		options.Feed = "continuous"
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	if options.Feed != "continuous" && options.Feed != "eventsource" {
+		return nil, fmt.Errorf("Use DBUpdates for %v feeds", options.Feed)
+	}
+	f := &DBUpdatesFeed{conn: conn, auth: auth, options: options}
+	f.stream = newFeedStream(conn.Context(), "DB updates feed", Seq(options.Since),
+		options.Feed == "eventsource", options.Timeout > 0, f.open)
+	if err := f.stream.connect(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+//(re)opens the feed from the last seen sequence
+func (f *DBUpdatesFeed) open() (*http.Response, error) {
+	opts := f.options
+	opts.Since = string(f.stream.lastSeq)
+	return f.conn.dbUpdatesRequest(&opts, f.auth)
+}
+
+//Advances to the next update.
+//Returns false when the feed ends, is closed, or an error occurs.
+func (f *DBUpdatesFeed) Next() bool {
+	f.update = nil
+	update := DBUpdate{}
+	if !f.stream.nextRow(&update) {
+		return false
+	}
+	f.update = &update
+	return true
+}
+
+//Returns the current update
+func (f *DBUpdatesFeed) Update() *DBUpdate {
+	return f.update
+}
+
+//Returns the last sequence seen on the feed
+func (f *DBUpdatesFeed) LastSeq() Seq {
+	return f.stream.lastSeq
+}
+
+//Returns the error, if any, that ended the feed
+func (f *DBUpdatesFeed) Err() error {
+	return f.stream.err
+}
+
+//Closes the feed.
+//Safe to call from another goroutine to interrupt a blocked Next.
+func (f *DBUpdatesFeed) Close() error {
+	return f.stream.close()
+}
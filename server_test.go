@@ -0,0 +1,152 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServerInfo(t *testing.T) {
+	conn := getConnection(t)
+	info, err := conn.ServerInfo()
+	errorify(t, err)
+	if err == nil && (info.CouchDB != "Welcome" || info.Version == "") {
+		t.Errorf("Wrong server info: %v", info)
+	}
+	status, err := conn.Up()
+	errorify(t, err)
+	if err == nil && !status.IsUp() {
+		t.Errorf("Server is not up: %v", status)
+	}
+	uuids, err := conn.UUIDs(3)
+	errorify(t, err)
+	if len(uuids) != 3 || uuids[0] == uuids[1] {
+		t.Errorf("Wrong UUIDs: %v", uuids)
+	}
+	_, err = conn.ActiveTasks(adminAuth)
+	errorify(t, err)
+}
+
+func TestDBUpdates(t *testing.T) {
+	conn := getConnection(t)
+	updates, err := conn.DBUpdates(&DBUpdatesOptions{Since: "now"}, adminAuth)
+	errorify(t, err)
+	if err != nil {
+		return
+	}
+	feed, err := conn.DBUpdatesFeed(&DBUpdatesOptions{
+		Since:     string(updates.LastSeq),
+		Heartbeat: 1000,
+	}, adminAuth)
+	if err != nil {
+		t.Fatalf("ERROR: %v", err)
+	}
+	defer feed.Close()
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	for feed.Next() {
+		if update := feed.Update(); update.DBName == dbName {
+			if update.Type != "created" {
+				t.Errorf("Wrong update type: %v", update.Type)
+			}
+			return
+		}
+	}
+	t.Errorf("Feed ended before the database was created: %v", feed.Err())
+}
+
+func TestActiveTaskTypes(t *testing.T) {
+	var tasks []ActiveTask
+	err := json.Unmarshal([]byte(`[
+		{"type": "indexer", "node": "n1", "database": "shards/a/db",
+			"design_document": "_design/colors", "progress": 40,
+			"changes_done": 400, "total_changes": 1000},
+		{"type": "replication", "doc_id": "rep", "source": "http://a/db/",
+			"target": "http://b/db/", "continuous": true,
+			"through_seq": "12-g1A", "docs_written": 12},
+		{"type": "view_compaction", "database": "db", "phase": "view",
+			"design_document": "_design/colors"},
+		{"type": "search_indexer", "index": "idx"}
+	]`), &tasks)
+	errorify(t, err)
+	if len(tasks) != 4 {
+		t.Fatalf("Wrong tasks: %v", tasks)
+	}
+	if tasks[0].Indexer == nil || tasks[0].Indexer.DesignDocument != "_design/colors" ||
+		tasks[0].Indexer.ChangesDone != 400 || tasks[0].Progress != 40 {
+		t.Errorf("Wrong indexer task: %v", tasks[0])
+	}
+	if tasks[1].Replication == nil || !tasks[1].Replication.Continuous ||
+		tasks[1].Replication.ThroughSeq != "12-g1A" || tasks[1].Indexer != nil {
+		t.Errorf("Wrong replication task: %v", tasks[1])
+	}
+	if tasks[2].Compaction == nil || tasks[2].Compaction.Phase != "view" {
+		t.Errorf("Wrong compaction task: %v", tasks[2])
+	}
+	var other struct {
+		Index string `json:"index"`
+	}
+	errorify(t, json.Unmarshal(tasks[3].Raw, &other))
+	if other.Index != "idx" {
+		t.Errorf("Wrong raw task: %v", string(tasks[3].Raw))
+	}
+}
+
+func TestUpMaintenanceMode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(404)
+			w.Write([]byte(`{"status":"maintenance_mode"}`))
+		}))
+	defer server.Close()
+	conn, err := createConnection(server.URL, time.Second)
+	errorify(t, err)
+	status, err := conn.Up()
+	errorify(t, err)
+	if err == nil && (status.IsUp() || status.Status != "maintenance_mode") {
+		t.Errorf("Wrong status: %v", status)
+	}
+}
+
+func TestDBUpdatesFeedReconnects(t *testing.T) {
+	sinces := []string{}
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			sinces = append(sinces, r.URL.Query().Get("since"))
+			switch len(sinces) {
+			case 1:
+				fmt.Fprintln(w, `{"db_name":"a","type":"created","seq":"1-x"}`)
+				fmt.Fprintln(w, "")
+				//the connection drops
+			case 2:
+				//an idle feed timed out by the server
+				fmt.Fprintln(w, `{"last_seq":"1-x"}`)
+			default:
+				fmt.Fprintln(w, `{"db_name":"b","type":"deleted","seq":"2-x"}`)
+				fmt.Fprintln(w, `{"last_seq":"2-x"}`)
+			}
+		}))
+	defer server.Close()
+	conn, err := createConnection(server.URL, time.Second)
+	errorify(t, err)
+	if _, err = conn.DBUpdates(&DBUpdatesOptions{Feed: "continuous"}, nil); err == nil {
+		t.Error("DBUpdates should refuse continuous feeds")
+	}
+	feed, err := conn.DBUpdatesFeed(nil, nil)
+	if err != nil {
+		t.Fatalf("ERROR: %v", err)
+	}
+	defer feed.Close()
+	names := ""
+	for i := 0; i < 2 && feed.Next(); i++ {
+		names += feed.Update().DBName + feed.Update().Type
+	}
+	errorify(t, feed.Err())
+	if names != "acreatedbdeleted" || feed.LastSeq() != "2-x" ||
+		len(sinces) != 3 || sinces[1] != "1-x" || sinces[2] != "1-x" {
+		t.Errorf("Wrong feed: %v %v %v", names, feed.LastSeq(), sinces)
+	}
+}
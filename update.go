@@ -0,0 +1,145 @@
+package couchdb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+//Returned by an Update mutator to leave the document as it is.
+//Update then returns the current revision without saving.
+var ErrSkipUpdate = errors.New("Update skipped")
+
+const (
+	defaultUpdateRetries    = 5
+	defaultUpdateBackoff    = 10 * time.Millisecond
+	defaultUpdateMaxBackoff = time.Second
+)
+
+type UpdateOptions struct {
+	//Number of times a conflicting save is retried (5 if not positive)
+	MaxRetries int
+	//Delay before the first retry, doubled for each one after (10ms if not positive)
+	Backoff time.Duration
+	//The longest delay between retries (1s if not positive).
+	//Each delay is randomized by up to half, so concurrent writers spread out.
+	MaxBackoff time.Duration
+	//If the document doesn't exist, call the mutator on a zero doc and create it
+	CreateIfMissing bool
+}
+
+//Reads the document into doc, calls mutate, and saves doc with the revision
+//that was read.  If someone else saved the document in the meantime,
+//the whole read-modify-write is retried.
+//doc must be a pointer, which mutate typically modifies through a closure:
+//	user := User{}
+//	rev, err := db.Update(id, &user, func() error {
+//		user.Visits++
+//		return nil
+//	})
+func (db *Database) Update(id string, doc interface{},
+	mutate func() error) (string, error) {
+	return db.UpdateWithOptions(id, doc, mutate, nil)
+}
+
+//Like Update, with control over retries and creating missing documents
+func (db *Database) UpdateWithOptions(id string, doc interface{},
+	mutate func() error, opts *UpdateOptions) (string, error) {
+	options := UpdateOptions{}
+	if opts != nil {
+		options = *opts
+	}
+	if options.MaxRetries <= 0 {
+		options.MaxRetries = defaultUpdateRetries
+	}
+	if options.Backoff <= 0 {
+		options.Backoff = defaultUpdateBackoff
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = defaultUpdateMaxBackoff
+	}
+	backoff := BackoffPolicy{BaseDelay: options.Backoff, MaxDelay: options.MaxBackoff}
+	target := reflect.ValueOf(doc)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return "", fmt.Errorf("Update needs a pointer to a document")
+	}
+	for attempt := 0; ; attempt++ {
+		//don't let fields from the last attempt leak into this one
+		resetDoc(target)
+		rev, err := db.Read(id, doc, nil)
+		if err != nil {
+			if !options.CreateIfMissing || !isStatus(err, 404) {
+				return "", err
+			}
+			resetDoc(target)
+			rev = ""
+		}
+		if err = mutate(); err == ErrSkipUpdate {
+			return rev, nil
+		} else if err != nil {
+			return "", err
+		}
+		newRev, err := db.Save(doc, id, rev)
+		if err == nil {
+			return newRev, nil
+		}
+		if !isStatus(err, 409) || attempt >= options.MaxRetries {
+			return "", err
+		}
+		select {
+		case <-time.After(backoff.backoff(attempt + 1)):
+		case <-db.Context().Done():
+			return "", db.Context().Err()
+		}
+	}
+}
+
+//Empties the document a pointer points to.
+//A map is replaced with an empty one, not nil, so the mutator can fill it in.
+func resetDoc(target reflect.Value) {
+	elem := target.Elem()
+	if elem.Kind() == reflect.Map {
+		elem.Set(reflect.MakeMap(elem.Type()))
+	} else {
+		elem.Set(reflect.Zero(elem.Type()))
+	}
+}
+
+//Checks whether err is a CouchDB error with the given status code
+func isStatus(err error, statusCode int) bool {
+	couchErr, ok := err.(*Error)
+	return ok && couchErr.StatusCode == statusCode
+}
+
+//Applies a change to the security document.
+//apply returns false if the document is already as it should be.
+//The security document has no revisions, so a concurrent writer can't be
+//detected up front; instead the document is read back after saving,
+//and the change made again if it was lost.
+func (db *Database) updateSecurity(apply func(sec *Security) bool) error {
+	backoff := BackoffPolicy{BaseDelay: defaultUpdateBackoff,
+		MaxDelay: defaultUpdateMaxBackoff}
+	for saves := 0; ; saves++ {
+		sec, err := db.GetSecurity()
+		if err != nil {
+			return err
+		}
+		if !apply(sec) {
+			return nil
+		}
+		if saves > defaultUpdateRetries {
+			return fmt.Errorf("Security document of %v kept changing", db.dbName)
+		}
+		if saves > 0 {
+			select {
+			case <-time.After(backoff.backoff(saves)):
+			case <-db.Context().Done():
+				return db.Context().Err()
+			}
+		}
+		if err = db.SaveSecurity(*sec); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package couchdb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type counterDoc struct {
+	Count int `json:"count"`
+}
+
+func TestUpdateRetriesConflicts(t *testing.T) {
+	puts := 0
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case "GET":
+				w.Header().Set("ETag", fmt.Sprintf(`"%v-abc"`, puts+1))
+				fmt.Fprintf(w, `{"_id":"counter","count":%v}`, puts)
+			case "PUT":
+				puts++
+				if puts < 3 {
+					w.WriteHeader(409)
+					w.Write([]byte(`{"error":"conflict","reason":"Document update conflict."}`))
+					return
+				}
+				w.Header().Set("ETag", `"4-abc"`)
+				w.WriteHeader(201)
+				w.Write([]byte(`{"ok":true}`))
+			}
+		}))
+	defer server.Close()
+	conn, err := createConnection(server.URL, time.Second)
+	errorify(t, err)
+	db := conn.SelectDB("db", nil)
+
+	doc := counterDoc{}
+	reads := []int{}
+	rev, err := db.Update("counter", &doc, func() error {
+		reads = append(reads, doc.Count)
+		doc.Count++
+		return nil
+	})
+	errorify(t, err)
+	if rev != "4-abc" || fmt.Sprint(reads) != "[0 1 2]" {
+		t.Errorf("Expected 3 attempts, each on a fresh read: %v %v", rev, reads)
+	}
+
+	puts = 0
+	_, err = db.UpdateWithOptions("counter", &doc, func() error {
+		return nil
+	}, &UpdateOptions{MaxRetries: 1})
+	if !isStatus(err, 409) {
+		t.Errorf("Expected a conflict after 1 retry: %v", err)
+	}
+	if _, err = db.Update("counter", doc, func() error { return nil }); err == nil {
+		t.Error("Expected an error for a non-pointer")
+	}
+}
+
+func TestUpdateBackoffIsBounded(t *testing.T) {
+	puts := 0
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case "GET":
+				w.Header().Set("ETag", `"1-abc"`)
+				w.Write([]byte(`{"_id":"counter","count":1}`))
+			case "PUT":
+				puts++
+				w.WriteHeader(409)
+				w.Write([]byte(`{"error":"conflict","reason":"Document update conflict."}`))
+			}
+		}))
+	defer server.Close()
+	conn, err := createConnection(server.URL, time.Second)
+	errorify(t, err)
+	db := conn.SelectDB("db", nil)
+
+	//uncapped, the last of 20 doublings of 1ms would be over 8 minutes
+	start := time.Now()
+	doc := counterDoc{}
+	_, err = db.UpdateWithOptions("counter", &doc, func() error {
+		doc.Count++
+		return nil
+	}, &UpdateOptions{MaxRetries: 20, Backoff: time.Millisecond,
+		MaxBackoff: 5 * time.Millisecond})
+	if !isStatus(err, 409) || puts != 21 {
+		t.Errorf("Expected 21 conflicting saves, got %v: %v", puts, err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Backoff should be capped, took %v", elapsed)
+	}
+}
+
+func TestUpdateCreatesMapDoc(t *testing.T) {
+	saved := ""
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case "GET":
+				w.WriteHeader(404)
+				w.Write([]byte(`{"error":"not_found","reason":"missing"}`))
+			case "PUT":
+				body, _ := ioutil.ReadAll(r.Body)
+				saved = string(body)
+				w.Header().Set("ETag", `"1-abc"`)
+				w.WriteHeader(201)
+				w.Write([]byte(`{"ok":true}`))
+			}
+		}))
+	defer server.Close()
+	conn, err := createConnection(server.URL, time.Second)
+	errorify(t, err)
+	db := conn.SelectDB("db", nil)
+
+	doc := map[string]interface{}{}
+	rev, err := db.UpdateWithOptions("settings", &doc, func() error {
+		doc["theme"] = "dark"
+		return nil
+	}, &UpdateOptions{CreateIfMissing: true})
+	errorify(t, err)
+	if rev != "1-abc" || !strings.Contains(saved, `"theme":"dark"`) {
+		t.Errorf("Map doc not created: %v %v", rev, saved)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+
+	doc := counterDoc{}
+	_, err := db.Update("counter", &doc, func() error {
+		doc.Count++
+		return nil
+	})
+	if !isStatus(err, 404) {
+		t.Errorf("Expected a 404: %v", err)
+	}
+	_, err = db.UpdateWithOptions("counter", &doc, func() error {
+		doc.Count++
+		return nil
+	}, &UpdateOptions{CreateIfMissing: true})
+	errorify(t, err)
+
+	//concurrent increments must not be lost
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			doc := counterDoc{}
+			_, err := db.UpdateWithOptions("counter", &doc, func() error {
+				doc.Count++
+				return nil
+			}, &UpdateOptions{MaxRetries: 20})
+			errorify(t, err)
+		}()
+	}
+	wg.Wait()
+
+	rev, err := db.Read("counter", &doc, nil)
+	errorify(t, err)
+	if doc.Count != 6 {
+		t.Errorf("Expected a count of 6, got %v", doc.Count)
+	}
+	skipRev, err := db.Update("counter", &doc, func() error {
+		return ErrSkipUpdate
+	})
+	errorify(t, err)
+	if skipRev != rev {
+		t.Errorf("Skipping should return the current rev: %v %v", skipRev, rev)
+	}
+}
@@ -0,0 +1,77 @@
+package couchdb
+
+import (
+	"strconv"
+)
+
+//The result of one query of a multi-query request
+type ViewQueryResult struct {
+	TotalRows int64     `json:"total_rows"`
+	Offset    int64     `json:"offset"`
+	UpdateSeq Seq       `json:"update_seq,omitempty"`
+	Rows      []ViewRow `json:"rows"`
+}
+
+//Runs several queries against a view in one request.
+//Returns a result for each query, in order.  Requires CouchDB 2.2 or later.
+func (db *Database) QueryViewMulti(designDoc string, view string,
+	queries []ViewQuery) ([]ViewQueryResult, error) {
+	return db.multiQuery(queries, db.dbName, "_design", designDoc,
+		"_view", view, "queries")
+}
+
+//Runs several queries against _all_docs in one request.
+func (db *Database) QueryAllDocsMulti(queries []ViewQuery) ([]ViewQueryResult, error) {
+	return db.multiQuery(queries, db.dbName, "_all_docs", "queries")
+}
+
+//Runs several queries against _design_docs in one request.
+func (db *Database) QueryDesignDocsMulti(queries []ViewQuery) ([]ViewQueryResult, error) {
+	return db.multiQuery(queries, db.dbName, "_design_docs", "queries")
+}
+
+//Runs several queries against _local_docs in one request.
+func (db *Database) QueryLocalDocsMulti(queries []ViewQuery) ([]ViewQueryResult, error) {
+	return db.multiQuery(queries, db.dbName, "_local_docs", "queries")
+}
+
+func (db *Database) multiQuery(queries []ViewQuery,
+	pathSegments ...string) ([]ViewQueryResult, error) {
+	if len(queries) == 0 {
+		return []ViewQueryResult{}, nil
+	}
+	bodies := []map[string]interface{}{}
+	for i := range queries {
+		body, err := queries[i].body()
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, body)
+	}
+	url, err := buildUrl(pathSegments...)
+	if err != nil {
+		return nil, err
+	}
+	var headers = make(map[string]string)
+	headers["Content-Type"] = "application/json"
+	headers["Accept"] = "application/json"
+	data, numBytes, err := encodeData(map[string]interface{}{"queries": bodies})
+	if err != nil {
+		return nil, err
+	}
+	headers["Content-Length"] = strconv.Itoa(numBytes)
+	if numBytes > 4000 {
+		headers["Expect"] = "100-continue"
+	}
+	resp, err := db.connection.request("POST", url, data, headers, db.auth)
+	if err != nil {
+		return nil, err
+	}
+	var results struct {
+		Results []ViewQueryResult `json:"results"`
+	}
+	if err = parseBody(resp, &results); err != nil {
+		return nil, err
+	}
+	return results.Results, nil
+}
@@ -0,0 +1,87 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestViewQueryBody(t *testing.T) {
+	q := ViewQuery{
+		Keys:          []interface{}{"a", 2},
+		StartKeyDocID: "doc1",
+		IncludeDocs:   true,
+		Limit:         5,
+		Update:        "lazy",
+	}
+	body, err := q.body()
+	errorify(t, err)
+	data, err := json.Marshal(body)
+	errorify(t, err)
+	expected := `{"include_docs":true,"keys":["a",2],"limit":5,` +
+		`"startkey_docid":"doc1","update":"lazy"}`
+	if string(data) != expected {
+		t.Errorf("Expected %v, got %v", expected, string(data))
+	}
+}
+
+func TestQueryViewMulti(t *testing.T) {
+	conn := getConnection(t)
+	requireServer(t, conn, "2.2")
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+	createLotsDocs(t, db)
+	designDoc := DesignDocument{
+		Language: "javascript",
+		Views: map[string]View{
+			"notes": View{Map: "function(doc) { emit(doc.Note, doc.Title); }"},
+		},
+		Lists: map[string]string{},
+	}
+	_, err := db.SaveDesignDoc("colors", designDoc, "")
+	errorify(t, err)
+
+	results, err := db.QueryViewMulti("colors", "notes", []ViewQuery{
+		{Key: "magenta"},
+		{Keys: []interface{}{"purple", "chartreuse"}, Limit: 2},
+	})
+	errorify(t, err)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %v", len(results))
+	}
+	if len(results[0].Rows) != 5 || len(results[1].Rows) != 2 {
+		t.Errorf("Wrong row counts: %v %v", len(results[0].Rows), len(results[1].Rows))
+	}
+	var key string
+	errorify(t, results[1].Rows[0].DecodeKey(&key))
+	if key != "purple" {
+		t.Errorf("Wrong key: %v", key)
+	}
+
+	results, err = db.QueryAllDocsMulti([]ViewQuery{
+		{Limit: 1},
+		{Keys: []interface{}{"nosuchdoc"}},
+	})
+	errorify(t, err)
+	if len(results) != 2 || results[0].TotalRows != 11 || len(results[0].Rows) != 1 {
+		t.Fatalf("Wrong _all_docs results: %v", results)
+	}
+	if err := results[1].Rows[0].RowError(); err == nil {
+		t.Error("Expected a not_found row")
+	}
+
+	results, err = db.QueryDesignDocsMulti([]ViewQuery{{IncludeDocs: true}})
+	errorify(t, err)
+	if len(results) != 1 || len(results[0].Rows) != 1 ||
+		results[0].Rows[0].ID != "_design/colors" {
+		t.Errorf("Wrong _design_docs results: %v", results)
+	}
+
+	err = db.SaveLocal(map[string]string{"hello": "world"}, "checkpoint")
+	errorify(t, err)
+	results, err = db.QueryLocalDocsMulti([]ViewQuery{{}})
+	errorify(t, err)
+	if len(results) != 1 || len(results[0].Rows) != 1 {
+		t.Errorf("Wrong _local_docs results: %v", results)
+	}
+}
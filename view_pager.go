@@ -0,0 +1,172 @@
+package couchdb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+//Pages through a view by key, without using skip.
+//Each page carries opaque tokens for the pages either side of it,
+//which encode the key and document ID to continue from,
+//so duplicate keys are paged through correctly.
+//	pager := db.NewViewPager("ddoc", "view", &ViewQuery{Descending: true}, 20)
+//	page, err := pager.Page(token) //"" for the first page
+type ViewPager struct {
+	db        *Database
+	designDoc string
+	view      string
+	query     ViewQuery
+	pageSize  int
+}
+
+//A page of rows.  Next and Prev are the tokens of the following
+//and preceding pages, and are empty if there are none.
+type ViewPage struct {
+	Rows      []ViewRow
+	TotalRows int64
+	Next      string
+	Prev      string
+}
+
+//Where a page starts.  A token for the next page names its first row;
+//a token for the previous page names the row following it.
+type pageToken struct {
+	Key      json.RawMessage `json:"k"`
+	DocID    string          `json:"d"`
+	Backward bool            `json:"b,omitempty"`
+}
+
+func (token *pageToken) encode() string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageToken(s string) (*pageToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid page token")
+	}
+	token := pageToken{}
+	if err = json.Unmarshal(data, &token); err != nil || token.Key == nil {
+		return nil, fmt.Errorf("Invalid page token")
+	}
+	return &token, nil
+}
+
+//Creates a pager over a view.  The query may set a key range, Descending,
+//IncludeDocs and similar options, but not Key, Keys, Skip or Limit.
+//Reduce is always turned off, since paging needs the document IDs.
+func (db *Database) NewViewPager(designDoc string, view string,
+	q *ViewQuery, pageSize int) *ViewPager {
+	pager := &ViewPager{
+		db:        db,
+		designDoc: designDoc,
+		view:      view,
+		pageSize:  pageSize,
+	}
+	if q != nil {
+		pager.query = *q
+	}
+	return pager
+}
+
+//Fetches the page for a token, or the first page if the token is empty
+func (p *ViewPager) Page(token string) (*ViewPage, error) {
+	if p.pageSize <= 0 {
+		return nil, fmt.Errorf("Page size must be positive")
+	}
+	if p.query.Key != nil || p.query.Keys != nil || p.query.Skip > 0 ||
+		p.query.Limit > 0 || p.query.Group || p.query.GroupLevel > 0 {
+		return nil, fmt.Errorf("Key, Keys, Skip, Limit and Group can't be used when paging")
+	}
+	if token == "" {
+		return p.forward(nil)
+	}
+	start, err := decodePageToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if start.Backward {
+		return p.backward(start)
+	}
+	return p.forward(start)
+}
+
+func (p *ViewPager) fetch(q *ViewQuery) (*ViewQueryResult, error) {
+	reduce := false
+	q.Reduce = &reduce
+	result := ViewQueryResult{}
+	err := p.db.QueryView(p.designDoc, p.view, q, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+//reads a page in the view's order, starting at start (inclusive)
+func (p *ViewPager) forward(start *pageToken) (*ViewPage, error) {
+	q := p.query
+	if start != nil {
+		q.StartKey = start.Key
+		q.StartKeyDocID = start.DocID
+	}
+	q.Limit = p.pageSize + 1
+	result, err := p.fetch(&q)
+	if err != nil {
+		return nil, err
+	}
+	page := ViewPage{Rows: result.Rows, TotalRows: result.TotalRows}
+	if len(page.Rows) > p.pageSize {
+		next := page.Rows[p.pageSize]
+		page.Next = (&pageToken{Key: next.Key, DocID: next.ID}).encode()
+		page.Rows = page.Rows[:p.pageSize]
+	}
+	if start != nil && len(page.Rows) > 0 {
+		first := page.Rows[0]
+		page.Prev = (&pageToken{Key: first.Key, DocID: first.ID,
+			Backward: true}).encode()
+	}
+	return &page, nil
+}
+
+//reads the page before start, walking the view in reverse
+func (p *ViewPager) backward(start *pageToken) (*ViewPage, error) {
+	inclusiveEnd := true
+	q := p.query
+	q.Descending = !p.query.Descending
+	q.StartKey = start.Key
+	q.StartKeyDocID = start.DocID
+	q.EndKey = p.query.StartKey
+	q.EndKeyDocID = p.query.StartKeyDocID
+	q.InclusiveEnd = &inclusiveEnd
+	//the start row itself, a page, and one more to tell if there's another page
+	q.Limit = p.pageSize + 2
+	result, err := p.fetch(&q)
+	if err != nil {
+		return nil, err
+	}
+	rows := result.Rows
+	if len(rows) > 0 && rows[0].ID == start.DocID &&
+		string(rows[0].Key) == string(start.Key) {
+		rows = rows[1:]
+	}
+	more := len(rows) > p.pageSize
+	if more {
+		rows = rows[:p.pageSize]
+	}
+	page := ViewPage{
+		Rows:      make([]ViewRow, len(rows)),
+		TotalRows: result.TotalRows,
+		Next:      (&pageToken{Key: start.Key, DocID: start.DocID}).encode(),
+	}
+	for i, row := range rows {
+		page.Rows[len(rows)-1-i] = row
+	}
+	if more {
+		first := page.Rows[0]
+		page.Prev = (&pageToken{Key: first.Key, DocID: first.ID,
+			Backward: true}).encode()
+	}
+	return &page, nil
+}
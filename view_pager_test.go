@@ -0,0 +1,92 @@
+package couchdb
+
+import (
+	"testing"
+)
+
+func TestPageToken(t *testing.T) {
+	token := pageToken{Key: []byte(`["a",1]`), DocID: "doc1", Backward: true}
+	decoded, err := decodePageToken(token.encode())
+	errorify(t, err)
+	if string(decoded.Key) != `["a",1]` || decoded.DocID != "doc1" || !decoded.Backward {
+		t.Errorf("Wrong token: %v", decoded)
+	}
+	for _, bad := range []string{"!!!", "e30"} {
+		if _, err := decodePageToken(bad); err == nil {
+			t.Errorf("%v should be rejected", bad)
+		}
+	}
+}
+
+func TestViewPager(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+	createLotsDocs(t, db)
+	designDoc := DesignDocument{
+		Language: "javascript",
+		Views: map[string]View{
+			"notes": View{
+				Map:    "function(doc) { emit(doc.Note, null); }",
+				Reduce: "_count",
+			},
+		},
+		Lists: map[string]string{},
+	}
+	_, err := db.SaveDesignDoc("colors", designDoc, "")
+	errorify(t, err)
+
+	for _, descending := range []bool{false, true} {
+		//only 2 distinct keys, so pages split runs of duplicates
+		pager := db.NewViewPager("colors", "notes", &ViewQuery{Descending: descending}, 3)
+		pages := [][]string{}
+		tokens := []string{""}
+		for {
+			page, err := pager.Page(tokens[len(tokens)-1])
+			errorify(t, err)
+			if err != nil {
+				return
+			}
+			ids := []string{}
+			for _, row := range page.Rows {
+				ids = append(ids, row.ID)
+			}
+			pages = append(pages, ids)
+			if page.Next == "" {
+				break
+			}
+			tokens = append(tokens, page.Next)
+		}
+		if len(pages) != 4 || len(pages[3]) != 1 {
+			t.Fatalf("Expected pages of 3, 3, 3 and 1: %v", pages)
+		}
+		seen := make(map[string]bool)
+		for _, ids := range pages {
+			for _, id := range ids {
+				if seen[id] {
+					t.Errorf("%v appears twice", id)
+				}
+				seen[id] = true
+			}
+		}
+
+		//and back again
+		page, err := pager.Page(tokens[len(tokens)-1])
+		errorify(t, err)
+		for i := len(pages) - 2; i >= 0; i-- {
+			page, err = pager.Page(page.Prev)
+			errorify(t, err)
+			if len(page.Rows) != len(pages[i]) || page.Rows[0].ID != pages[i][0] {
+				t.Errorf("Page %v differs going backward", i)
+			}
+		}
+		if page.Prev != "" {
+			t.Error("The first page should have no previous page")
+		}
+	}
+
+	if _, err := db.NewViewPager("colors", "notes", &ViewQuery{Skip: 1}, 3).Page(""); err == nil {
+		t.Error("Skip should be rejected")
+	}
+}
@@ -0,0 +1,213 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+//Keys are sent in the body of a POST, rather than the query string,
+//once their JSON encoding is longer than this.
+const maxQueryKeysLength = 1500
+
+//Options for querying a view, _all_docs or a list function.
+//Key, StartKey, EndKey and Keys hold any JSON value and are encoded for you;
+//a nil Key, StartKey or EndKey is left out of the query.
+//See: http://docs.couchdb.org/en/2.1.1/api/ddoc/views.html
+type ViewQuery struct {
+	Key           interface{}
+	Keys          []interface{}
+	StartKey      interface{}
+	StartKeyDocID string
+	EndKey        interface{}
+	EndKeyDocID   string
+	InclusiveEnd  *bool //defaults to true
+	Descending    bool
+	Group         bool
+	GroupLevel    int
+	Reduce        *bool //defaults to true if the view has a reduce function
+	IncludeDocs   bool
+	Conflicts     bool //only with IncludeDocs
+	Attachments   bool //only with IncludeDocs
+	Limit         int
+	Skip          int
+	Stable        bool
+	Update        string //true, false or lazy
+	UpdateSeq     bool
+}
+
+//Returns the query string parameters for the query, including keys.
+func (q *ViewQuery) Values() (url.Values, error) {
+	params, err := q.values()
+	if err != nil {
+		return nil, err
+	}
+	if q != nil && q.Keys != nil {
+		keys, err := json.Marshal(q.Keys)
+		if err != nil {
+			return nil, err
+		}
+		params.Set("keys", string(keys))
+	}
+	return params, nil
+}
+
+//the parameters apart from keys
+func (q *ViewQuery) values() (url.Values, error) {
+	params := url.Values{}
+	if q == nil {
+		return params, nil
+	}
+	if q.Key != nil && q.Keys != nil {
+		return nil, fmt.Errorf("Key and Keys may not both be set")
+	}
+	if q.Limit < 0 || q.Skip < 0 || q.GroupLevel < 0 {
+		return nil, fmt.Errorf("Limit, Skip and GroupLevel must not be negative")
+	}
+	switch q.Update {
+	case "", "true", "false", "lazy":
+	default:
+		return nil, fmt.Errorf("Invalid Update value: %v", q.Update)
+	}
+	for name, key := range map[string]interface{}{
+		"key":      q.Key,
+		"startkey": q.StartKey,
+		"endkey":   q.EndKey,
+	} {
+		if key == nil {
+			continue
+		}
+		encoded, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		params.Set(name, string(encoded))
+	}
+	if q.StartKeyDocID != "" {
+		params.Set("startkey_docid", q.StartKeyDocID)
+	}
+	if q.EndKeyDocID != "" {
+		params.Set("endkey_docid", q.EndKeyDocID)
+	}
+	if q.InclusiveEnd != nil {
+		params.Set("inclusive_end", strconv.FormatBool(*q.InclusiveEnd))
+	}
+	if q.Reduce != nil {
+		params.Set("reduce", strconv.FormatBool(*q.Reduce))
+	}
+	flags := map[string]bool{
+		"descending":   q.Descending,
+		"group":        q.Group,
+		"include_docs": q.IncludeDocs,
+		"conflicts":    q.Conflicts,
+		"attachments":  q.Attachments,
+		"stable":       q.Stable,
+		"update_seq":   q.UpdateSeq,
+	}
+	for name, set := range flags {
+		if set {
+			params.Set(name, "true")
+		}
+	}
+	if q.GroupLevel > 0 {
+		params.Set("group_level", strconv.Itoa(q.GroupLevel))
+	}
+	if q.Limit > 0 {
+		params.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Skip > 0 {
+		params.Set("skip", strconv.Itoa(q.Skip))
+	}
+	if q.Update != "" {
+		params.Set("update", q.Update)
+	}
+	return params, nil
+}
+
+//Queries a view-like resource (a view, _all_docs or a list) at the given
+//path.  Keys go in the query string unless they are too long,
+//in which case they are POSTed.
+func (db *Database) viewRequest(q *ViewQuery,
+	pathSegments ...string) (*http.Response, error) {
+	params, err := q.values()
+	if err != nil {
+		return nil, err
+	}
+	var keys []byte
+	if q != nil && q.Keys != nil {
+		if keys, err = json.Marshal(q.Keys); err != nil {
+			return nil, err
+		}
+	}
+	var headers = make(map[string]string)
+	headers["Accept"] = "application/json"
+	method := "GET"
+	var data io.Reader
+	if len(keys) > maxQueryKeysLength {
+		var numBytes int
+		data, numBytes, err = encodeData(map[string]json.RawMessage{"keys": keys})
+		if err != nil {
+			return nil, err
+		}
+		method = "POST"
+		headers["Content-Type"] = "application/json"
+		headers["Content-Length"] = strconv.Itoa(numBytes)
+		if numBytes > 4000 {
+			headers["Expect"] = "100-continue"
+		}
+	} else if keys != nil {
+		params.Set("keys", string(keys))
+	}
+	url, err := buildParamUrl(params, pathSegments...)
+	if err != nil {
+		return nil, err
+	}
+	return db.connection.request(method, url, data, headers, db.auth)
+}
+
+//Get the results of a view, decoded into results.
+func (db *Database) QueryView(designDoc string, view string,
+	q *ViewQuery, results interface{}) error {
+	resp, err := db.viewRequest(q, db.dbName, "_design", designDoc, "_view", view)
+	if err != nil {
+		return err
+	}
+	return parseBody(resp, &results)
+}
+
+//Get the result of a list function applied to a view.
+//This assumes your list function in couchdb returns JSON
+func (db *Database) QueryList(designDoc string, list string, view string,
+	q *ViewQuery, results interface{}) error {
+	resp, err := db.viewRequest(q, db.dbName, "_design", designDoc,
+		"_list", list, view)
+	if err != nil {
+		return err
+	}
+	return parseBody(resp, &results)
+}
+
+//Returns the query as a JSON object, for the queries endpoints
+func (q *ViewQuery) body() (map[string]interface{}, error) {
+	params, err := q.Values()
+	if err != nil {
+		return nil, err
+	}
+	body := make(map[string]interface{})
+	for name := range params {
+		value := params.Get(name)
+		switch {
+		case name == "startkey_docid" || name == "endkey_docid":
+			body[name] = value
+		case name == "update" && value == "lazy":
+			body[name] = value
+		default:
+			//everything else is already valid JSON
+			body[name] = json.RawMessage(value)
+		}
+	}
+	return body, nil
+}
@@ -0,0 +1,118 @@
+package couchdb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestViewQueryValues(t *testing.T) {
+	inclusiveEnd := false
+	q := ViewQuery{
+		StartKey:     []interface{}{"a", 1},
+		EndKey:       "z",
+		InclusiveEnd: &inclusiveEnd,
+		IncludeDocs:  true,
+		GroupLevel:   2,
+		Limit:        10,
+		Update:       "lazy",
+	}
+	params, err := q.Values()
+	errorify(t, err)
+	expected := map[string]string{
+		"startkey":      `["a",1]`,
+		"endkey":        `"z"`,
+		"inclusive_end": "false",
+		"include_docs":  "true",
+		"group_level":   "2",
+		"limit":         "10",
+		"update":        "lazy",
+	}
+	if len(params) != len(expected) {
+		t.Errorf("Unexpected params: %v", params)
+	}
+	for name, value := range expected {
+		if params.Get(name) != value {
+			t.Errorf("%v: expected %v, got %v", name, value, params.Get(name))
+		}
+	}
+
+	q = ViewQuery{Keys: []interface{}{"a", 1, nil}}
+	params, err = q.Values()
+	errorify(t, err)
+	if params.Get("keys") != `["a",1,null]` {
+		t.Errorf("Wrong keys: %v", params.Get("keys"))
+	}
+
+	for _, bad := range []ViewQuery{
+		{Key: "a", Keys: []interface{}{"b"}},
+		{Limit: -1},
+		{Update: "sometimes"},
+	} {
+		if _, err := bad.Values(); err == nil {
+			t.Errorf("%v should be rejected", bad)
+		}
+	}
+}
+
+func TestQueryView(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+	createLotsDocs(t, db)
+	designDoc := DesignDocument{
+		Language: "javascript",
+		Views: map[string]View{
+			"notes": View{
+				Map:    "function(doc) { emit([doc.Note, doc.Title], 1); }",
+				Reduce: "_count",
+			},
+		},
+		Lists: map[string]string{},
+	}
+	_, err := db.SaveDesignDoc("colors", designDoc, "")
+	errorify(t, err)
+
+	var grouped struct {
+		Rows []struct {
+			Key   []string `json:"key"`
+			Value int      `json:"value"`
+		} `json:"rows"`
+	}
+	errorify(t, db.QueryView("colors", "notes",
+		&ViewQuery{Group: true, GroupLevel: 1}, &grouped))
+	if len(grouped.Rows) != 2 || grouped.Rows[0].Key[0] != "magenta" ||
+		grouped.Rows[0].Value != 5 {
+		t.Errorf("Wrong grouped rows: %v", grouped.Rows)
+	}
+
+	reduce := false
+	var ranged struct {
+		Rows []struct {
+			Key []string      `json:"key"`
+			Doc *TestDocument `json:"doc"`
+		} `json:"rows"`
+	}
+	errorify(t, db.QueryView("colors", "notes", &ViewQuery{
+		StartKey:    []interface{}{"purple"},
+		EndKey:      []interface{}{"purple", map[string]interface{}{}},
+		Reduce:      &reduce,
+		IncludeDocs: true,
+	}, &ranged))
+	if len(ranged.Rows) != 5 || ranged.Rows[0].Doc == nil ||
+		ranged.Rows[0].Doc.Note != "purple" {
+		t.Errorf("Wrong ranged rows: %v", ranged.Rows)
+	}
+
+	//enough keys to be POSTed
+	keys := []interface{}{[]string{"magenta", "TheDoc -- 0"}}
+	for len(keys) < 200 {
+		keys = append(keys, []string{"nonexistent", strings.Repeat("x", 20)})
+	}
+	ranged.Rows = nil
+	errorify(t, db.QueryView("colors", "notes",
+		&ViewQuery{Keys: keys, Reduce: &reduce}, &ranged))
+	if len(ranged.Rows) != 1 {
+		t.Errorf("Expected 1 row, got %v", len(ranged.Rows))
+	}
+}
@@ -0,0 +1,256 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+//Error for a row of a multi-key query whose key wasn't found,
+//e.g. {"key": "missing", "error": "not_found"}
+type ViewRowError struct {
+	Key       json.RawMessage
+	ErrorCode string
+}
+
+func (err *ViewRowError) Error() string {
+	return fmt.Sprintf("View row %s: %v", err.Key, err.ErrorCode)
+}
+
+//A single row of a view, _all_docs or similar response.
+//Key, Value and Doc are left undecoded until asked for.
+//Error is set instead for a key of a multi-key query that wasn't found.
+type ViewRow struct {
+	ID    string          `json:"id,omitempty"`
+	Key   json.RawMessage `json:"key"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Doc   json.RawMessage `json:"doc,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+//Decodes the row's key into v
+func (row *ViewRow) DecodeKey(v interface{}) error {
+	return decodeRowField(row.Key, v)
+}
+
+//Decodes the row's value into v
+func (row *ViewRow) DecodeValue(v interface{}) error {
+	return decodeRowField(row.Value, v)
+}
+
+//Decodes the row's document into v.
+//Only present if the query set IncludeDocs; the document is
+//null if it has been deleted or the value emitted an _id that doesn't exist.
+func (row *ViewRow) DecodeDoc(v interface{}) error {
+	if row.Doc == nil {
+		return fmt.Errorf("No document in row")
+	}
+	return decodeRowField(row.Doc, v)
+}
+
+//Returns a *ViewRowError if this is an error row, otherwise nil
+func (row *ViewRow) RowError() error {
+	if row.Error == "" {
+		return nil
+	}
+	return &ViewRowError{Key: row.Key, ErrorCode: row.Error}
+}
+
+//Streams the rows of a view response one at a time,
+//so large results never need to fit in memory.
+//	rows, err := db.StreamView("ddoc", "view", &query)
+//	...
+//	defer rows.Close()
+//	for rows.Next() {
+//		err := rows.Value(&value)
+//		...
+//	}
+//	if err := rows.Err(); err != nil {
+//		...
+//	}
+type ViewRows struct {
+	resp      *http.Response
+	decoder   *json.Decoder
+	totalRows int64
+	offset    int64
+	updateSeq Seq
+	row       ViewRow
+	done      bool
+	err       error
+}
+
+//Queries a view and returns an iterator over its rows.
+//The caller must Close it, unless Next has returned false.
+func (db *Database) StreamView(designDoc string, view string,
+	q *ViewQuery) (*ViewRows, error) {
+	resp, err := db.viewRequest(q, db.dbName, "_design", designDoc, "_view", view)
+	if err != nil {
+		return nil, err
+	}
+	return newViewRows(resp)
+}
+
+//reads the fields preceding the rows
+func newViewRows(resp *http.Response) (*ViewRows, error) {
+	rows := &ViewRows{resp: resp, decoder: json.NewDecoder(resp.Body)}
+	if err := rows.expectDelim('{'); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	for {
+		if !rows.decoder.More() {
+			//no rows at all
+			rows.done = true
+			resp.Body.Close()
+			return rows, nil
+		}
+		name, err := rows.decoder.Token()
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		if name == "rows" {
+			if err := rows.expectDelim('['); err != nil {
+				resp.Body.Close()
+				return nil, err
+			}
+			return rows, nil
+		}
+		if err := rows.readField(name); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+}
+
+func (rows *ViewRows) expectDelim(delim json.Delim) error {
+	token, err := rows.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("Unexpected view response: expected %v, got %v",
+			delim, token)
+	}
+	return nil
+}
+
+//decodes a top level field other than rows
+func (rows *ViewRows) readField(name json.Token) error {
+	switch name {
+	case "total_rows":
+		return rows.decoder.Decode(&rows.totalRows)
+	case "offset":
+		return rows.decoder.Decode(&rows.offset)
+	case "update_seq":
+		return rows.decoder.Decode(&rows.updateSeq)
+	default:
+		var skip json.RawMessage
+		return rows.decoder.Decode(&skip)
+	}
+}
+
+//Advances to the next row.
+//Returns false once every row has been read or an error occurred,
+//after which the response is closed.
+func (rows *ViewRows) Next() bool {
+	if rows.done {
+		return false
+	}
+	if !rows.decoder.More() {
+		rows.finish()
+		return false
+	}
+	rows.row = ViewRow{}
+	if err := rows.decoder.Decode(&rows.row); err != nil {
+		rows.err = err
+		rows.Close()
+		return false
+	}
+	return true
+}
+
+//reads the fields following the rows
+func (rows *ViewRows) finish() {
+	defer rows.Close()
+	if rows.err = rows.expectDelim(']'); rows.err != nil {
+		return
+	}
+	for rows.decoder.More() {
+		name, err := rows.decoder.Token()
+		if err != nil {
+			rows.err = err
+			return
+		}
+		if rows.err = rows.readField(name); rows.err != nil {
+			return
+		}
+	}
+}
+
+//Closes the response.  Safe to call more than once.
+func (rows *ViewRows) Close() error {
+	if rows.done {
+		return nil
+	}
+	rows.done = true
+	return rows.resp.Body.Close()
+}
+
+//Returns the error that stopped the iteration, if any
+func (rows *ViewRows) Err() error {
+	return rows.err
+}
+
+//The total number of rows in the view, not just the ones returned.
+//Not set for reduce queries.
+func (rows *ViewRows) TotalRows() int64 {
+	return rows.totalRows
+}
+
+func (rows *ViewRows) Offset() int64 {
+	return rows.offset
+}
+
+//Only set if the query asked for UpdateSeq
+func (rows *ViewRows) UpdateSeq() Seq {
+	return rows.updateSeq
+}
+
+//The ID of the document that emitted the current row.
+//Empty for reduce rows and error rows.
+func (rows *ViewRows) ID() string {
+	return rows.row.ID
+}
+
+//Decodes the current row's key into v
+func (rows *ViewRows) Key(v interface{}) error {
+	return rows.row.DecodeKey(v)
+}
+
+//Decodes the current row's value into v
+func (rows *ViewRows) Value(v interface{}) error {
+	return rows.row.DecodeValue(v)
+}
+
+//Decodes the current row's document into v.  See ViewRow.DecodeDoc.
+func (rows *ViewRows) Doc(v interface{}) error {
+	return rows.row.DecodeDoc(v)
+}
+
+//Returns a *ViewRowError if the current row is an error row, otherwise nil
+func (rows *ViewRows) RowError() error {
+	return rows.row.RowError()
+}
+
+//Returns the current row
+func (rows *ViewRows) Row() ViewRow {
+	return rows.row
+}
+
+func decodeRowField(data json.RawMessage, v interface{}) error {
+	if data == nil {
+		return fmt.Errorf("No such field in row")
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,102 @@
+package couchdb
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestViewRowsParsing(t *testing.T) {
+	body := `{"total_rows":3,"offset":1,"update_seq":"12-abc","rows":[
+		{"id":"doc1","key":["a",1],"value":{"count":2},"doc":{"Title":"one"}},
+		{"key":"missing","error":"not_found"},
+		{"id":"doc3","key":["c",3],"value":null}
+	],"extra":{"ignored":true}}`
+	resp := &http.Response{Body: ioutil.NopCloser(strings.NewReader(body))}
+	rows, err := newViewRows(resp)
+	errorify(t, err)
+	if rows.TotalRows() != 3 || rows.Offset() != 1 || rows.UpdateSeq() != "12-abc" {
+		t.Errorf("Wrong header: %v %v %v", rows.TotalRows(), rows.Offset(),
+			rows.UpdateSeq())
+	}
+	if !rows.Next() {
+		t.Fatalf("Expected a row: %v", rows.Err())
+	}
+	var key []interface{}
+	var value struct{ Count int }
+	doc := TestDocument{}
+	errorify(t, rows.Key(&key))
+	errorify(t, rows.Value(&value))
+	errorify(t, rows.Doc(&doc))
+	if rows.ID() != "doc1" || key[0] != "a" || value.Count != 2 || doc.Title != "one" {
+		t.Errorf("Wrong row: %v %v %v %v", rows.ID(), key, value, doc)
+	}
+	errorify(t, rows.RowError())
+	if !rows.Next() {
+		t.Fatalf("Expected a row: %v", rows.Err())
+	}
+	rowErr, ok := rows.RowError().(*ViewRowError)
+	if !ok || rowErr.ErrorCode != "not_found" || string(rowErr.Key) != `"missing"` {
+		t.Errorf("Expected a row error: %v", rows.RowError())
+	}
+	if !rows.Next() || rows.ID() != "doc3" {
+		t.Fatalf("Expected doc3: %v", rows.Err())
+	}
+	if err := rows.Doc(&doc); err == nil {
+		t.Error("Row has no doc")
+	}
+	if rows.Next() {
+		t.Error("Expected the end of the rows")
+	}
+	errorify(t, rows.Err())
+	errorify(t, rows.Close())
+
+	resp = &http.Response{Body: ioutil.NopCloser(strings.NewReader(`{"rows":[{"id":`))}
+	rows, err = newViewRows(resp)
+	errorify(t, err)
+	if rows.Next() || rows.Err() == nil {
+		t.Error("Expected an error for a truncated response")
+	}
+}
+
+func TestStreamView(t *testing.T) {
+	conn := getConnection(t)
+	dbName := createTestDb(t)
+	defer deleteTestDb(t, dbName)
+	db := conn.SelectDB(dbName, adminAuth)
+	createLotsDocs(t, db)
+	designDoc := DesignDocument{
+		Language: "javascript",
+		Views: map[string]View{
+			"notes": View{Map: "function(doc) { emit(doc.Note, doc.Title); }"},
+		},
+		Lists: map[string]string{},
+	}
+	_, err := db.SaveDesignDoc("colors", designDoc, "")
+	errorify(t, err)
+
+	rows, err := db.StreamView("colors", "notes",
+		&ViewQuery{Key: "purple", IncludeDocs: true})
+	errorify(t, err)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	count := 0
+	for rows.Next() {
+		var key, value string
+		doc := TestDocument{}
+		errorify(t, rows.Key(&key))
+		errorify(t, rows.Value(&value))
+		errorify(t, rows.Doc(&doc))
+		if key != "purple" || doc.Title != value || rows.ID() == "" {
+			t.Errorf("Wrong row: %v %v %v", key, value, doc)
+		}
+		count++
+	}
+	errorify(t, rows.Err())
+	if count != 5 || rows.TotalRows() != 10 {
+		t.Errorf("Expected 5 of 10 rows, got %v of %v", count, rows.TotalRows())
+	}
+}